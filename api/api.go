@@ -0,0 +1,450 @@
+// Package api serves the cards over HTTP. Browsers log in with a password
+// and get a session cookie; scripts send an API token as a bearer token.
+// Requests act on the caller's own cards, or with ?owner=name on the cards
+// of a user who granted the caller read or write permission.
+package api
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flashcards/dbinterface"
+	"github.com/flashcards/dict"
+)
+
+// SessionCookie is the name of the cookie holding a login session.
+const SessionCookie = "session"
+
+// Options configure the API.
+type Options struct {
+	// Signup lets anyone create an account with POST /accounts.
+	Signup bool
+	// SessionTTL is how long a login lasts. It defaults to two weeks.
+	SessionTTL time.Duration
+	// Timeout is how long the database may take over a request. It
+	// defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+type server struct {
+	dbc        *dbinterface.DatabaseConn
+	dictionary dict.Dictionary
+	opts       Options
+}
+
+// New returns a handler serving the cards of the database dbc is connected
+// to.
+func New(dbc *dbinterface.DatabaseConn, dictionary dict.Dictionary, opts Options) http.Handler {
+	if opts.SessionTTL == 0 {
+		opts.SessionTTL = 14 * 24 * time.Hour
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	s := &server{dbc: dbc, dictionary: dictionary, opts: opts}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /accounts", s.signup)
+	mux.HandleFunc("POST /login", s.login)
+	mux.HandleFunc("POST /logout", s.logout)
+	mux.HandleFunc("POST /tokens", s.authed(dbinterface.Read, s.createToken))
+	mux.HandleFunc("DELETE /tokens", s.authed(dbinterface.Read, s.revokeToken))
+	mux.HandleFunc("PUT /grants/{user}", s.authed(dbinterface.Write, s.grant))
+	mux.HandleFunc("DELETE /grants/{user}", s.authed(dbinterface.Write, s.revokeGrant))
+	mux.HandleFunc("GET /terms", s.owned(dbinterface.Read, s.listTerms))
+	mux.HandleFunc("POST /terms", s.owned(dbinterface.Write, s.addTerm))
+	mux.HandleFunc("PUT /terms/{term}", s.owned(dbinterface.Write, s.editTerm))
+	mux.HandleFunc("DELETE /terms/{term}", s.owned(dbinterface.Write, s.deleteTerm))
+	mux.HandleFunc("GET /terms/{term}/history", s.owned(dbinterface.Read, s.history))
+	mux.HandleFunc("POST /undo", s.owned(dbinterface.Write, s.undo))
+	mux.HandleFunc("POST /redo", s.owned(dbinterface.Write, s.redo))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), opts.Timeout)
+		defer cancel()
+		mux.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+type handler func(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, permission dbinterface.Permission) error
+
+// authed authenticates the caller and runs h with a connection for the
+// caller's cards, if the caller's token allows need.
+func (s *server) authed(need dbinterface.Permission, h handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dbc, permission, err := s.authenticate(r)
+		if err == nil && !permission.Allows(need) {
+			err = &errStatus{http.StatusForbidden, fmt.Sprintf("The token only has %s permission", permission)}
+		}
+		if err == nil {
+			err = h(w, r, dbc, permission)
+		}
+		if err != nil {
+			writeError(w, err)
+		}
+	}
+}
+
+// owned is like authed but runs h with a connection for the cards of the
+// user named by ?owner=, if that user granted the caller need.
+func (s *server) owned(need dbinterface.Permission, h handler) http.HandlerFunc {
+	return s.authed(need, func(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, permission dbinterface.Permission) error {
+		if owner := r.URL.Query().Get("owner"); owner != "" {
+			var err error
+			if dbc, err = dbinterface.AsOwner(r.Context(), dbc, owner, need); err != nil {
+				return err
+			}
+		}
+		return h(w, r, dbc, permission)
+	})
+}
+
+// authenticate checks the bearer token, or the session cookie if there is
+// none. Sessions have write permission.
+func (s *server) authenticate(r *http.Request) (*dbinterface.DatabaseConn, dbinterface.Permission, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return nil, "", &dbinterface.ErrInvalidCredentials{}
+		}
+		return dbinterface.Authenticate(r.Context(), s.dbc, token, dbinterface.APIToken)
+	}
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil, "", &dbinterface.ErrInvalidCredentials{}
+	}
+	return dbinterface.Authenticate(r.Context(), s.dbc, cookie.Value, dbinterface.SessionToken)
+}
+
+type credentials struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+}
+
+func (s *server) signup(w http.ResponseWriter, r *http.Request) {
+	if !s.opts.Signup {
+		writeError(w, &errStatus{http.StatusForbidden, "Signing up is turned off"})
+		return
+	}
+	var c credentials
+	if err := readJSON(r, &c); err != nil {
+		writeError(w, err)
+		return
+	}
+	if c.Name == "" {
+		writeError(w, &errStatus{http.StatusBadRequest, "A name is required"})
+		return
+	}
+	id, err := dbinterface.CreateAccount(r.Context(), s.dbc, c.Name, c.Password)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]int64{"id": id})
+}
+
+func (s *server) login(w http.ResponseWriter, r *http.Request) {
+	var c credentials
+	if err := readJSON(r, &c); err != nil {
+		writeError(w, err)
+		return
+	}
+	dbc, err := dbinterface.Login(r.Context(), s.dbc, c.Name, c.Password)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	token, err := dbinterface.CreateToken(r.Context(), dbc, dbinterface.SessionToken, dbinterface.Write, s.opts.SessionTTL)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(s.opts.SessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+	})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *server) logout(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		writeError(w, &dbinterface.ErrInvalidCredentials{})
+		return
+	}
+	dbc, _, err := dbinterface.Authenticate(r.Context(), s.dbc, cookie.Value, dbinterface.SessionToken)
+	if err == nil {
+		err = dbinterface.RevokeToken(r.Context(), dbc, cookie.Value)
+	}
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: SessionCookie, Path: "/", MaxAge: -1, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type tokenRequest struct {
+	Permission dbinterface.Permission `json:"permission"`
+	// TTL is a duration such as "720h". Tokens without one last until
+	// they are revoked.
+	TTL   string `json:"ttl,omitempty"`
+	Token string `json:"token,omitempty"`
+}
+
+func (s *server) createToken(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, permission dbinterface.Permission) error {
+	var req tokenRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	if req.Permission == "" {
+		req.Permission = dbinterface.Read
+	}
+	if _, err := dbinterface.ParsePermission(string(req.Permission)); err != nil {
+		return err
+	}
+	if !permission.Allows(req.Permission) {
+		return &errStatus{http.StatusForbidden, fmt.Sprintf("The token only has %s permission", permission)}
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(req.TTL); err != nil || ttl <= 0 {
+			return &errStatus{http.StatusBadRequest, fmt.Sprintf("Invalid ttl %q", req.TTL)}
+		}
+	}
+	token, err := dbinterface.CreateToken(r.Context(), dbc, dbinterface.APIToken, req.Permission, ttl)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusCreated, tokenRequest{Permission: req.Permission, TTL: req.TTL, Token: token})
+	return nil
+}
+
+func (s *server) revokeToken(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	var req tokenRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	if err := dbinterface.RevokeToken(r.Context(), dbc, req.Token); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *server) grant(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	var req struct {
+		Permission dbinterface.Permission `json:"permission"`
+	}
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	if err := dbinterface.Grant(r.Context(), dbc, r.PathValue("user"), req.Permission); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *server) revokeGrant(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	if err := dbinterface.RevokeGrant(r.Context(), dbc, r.PathValue("user")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// Term is a card as the API returns it.
+type Term struct {
+	Id         int64  `json:"id"`
+	Term       string `json:"term"`
+	Definition string `json:"definition"`
+}
+
+func (s *server) listTerms(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	terms, err := dbinterface.List(r.Context(), dbc)
+	if err != nil {
+		return err
+	}
+	list := []Term{}
+	for id, def := range terms {
+		list = append(list, Term{Id: id, Term: def.Term, Definition: def.Definition})
+	}
+	slices.SortFunc(list, func(a, b Term) int { return cmp.Compare(a.Id, b.Id) })
+	writeJSON(w, http.StatusOK, list)
+	return nil
+}
+
+func (s *server) addTerm(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	var req Term
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	ids, err := dbinterface.Add(r.Context(), dbc, req.Term, s.dictionary)
+	if err != nil {
+		return err
+	}
+	if ids == nil {
+		ids = []int64{}
+	}
+	writeJSON(w, http.StatusCreated, map[string][]int64{"ids": ids})
+	return nil
+}
+
+func (s *server) editTerm(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	var req Term
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	if err := dbinterface.Edit(r.Context(), dbc, r.PathValue("term"), req.Definition); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (s *server) deleteTerm(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	if err := dbinterface.Delete(r.Context(), dbc, r.PathValue("term")); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// Event is a history event as the API returns it.
+type Event struct {
+	TermId int64     `json:"termId"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail"`
+	At     time.Time `json:"at"`
+}
+
+func (s *server) history(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	events, err := dbinterface.History(r.Context(), dbc, r.PathValue("term"))
+	if err != nil {
+		return err
+	}
+	list := []Event{}
+	for _, e := range events {
+		list = append(list, Event{TermId: e.TermId, Kind: string(e.Kind), Detail: e.Detail, At: e.At})
+	}
+	writeJSON(w, http.StatusOK, list)
+	return nil
+}
+
+// Operation is an undone or redone journal entry as the API returns it.
+type Operation struct {
+	Op    string   `json:"op"`
+	Terms []string `json:"terms"`
+	Old   string   `json:"old,omitempty"`
+	New   string   `json:"new,omitempty"`
+}
+
+func (s *server) undo(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	return s.replay(w, r, dbc, dbinterface.Undo)
+}
+
+func (s *server) redo(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn, _ dbinterface.Permission) error {
+	return s.replay(w, r, dbc, dbinterface.Redo)
+}
+
+func (s *server) replay(w http.ResponseWriter, r *http.Request, dbc *dbinterface.DatabaseConn,
+	replay func(ctx context.Context, dbc *dbinterface.DatabaseConn, n int) ([]dbinterface.JournalEntry, error)) error {
+	n := 1
+	if param := r.URL.Query().Get("n"); param != "" {
+		var err error
+		if n, err = strconv.Atoi(param); err != nil || n < 1 {
+			return &errStatus{http.StatusBadRequest, fmt.Sprintf("Invalid n %q", param)}
+		}
+	}
+	entries, err := replay(r.Context(), dbc, n)
+	if err != nil {
+		return err
+	}
+	list := []Operation{}
+	for _, e := range entries {
+		list = append(list, Operation{Op: string(e.Op), Terms: e.Terms, Old: e.Old, New: e.New})
+	}
+	writeJSON(w, http.StatusOK, list)
+	return nil
+}
+
+// errStatus is an error reported with a status code of its own.
+type errStatus struct {
+	status  int
+	message string
+}
+
+func (e *errStatus) Error() string {
+	return e.message
+}
+
+func readJSON(r *http.Request, v any) error {
+	if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, 1<<20)).Decode(v); err != nil {
+		return &errStatus{http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err)}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// status returns the status code err is reported with.
+func status(err error) int {
+	var (
+		withStatus  *errStatus
+		notFound    *dbinterface.ErrNotFound
+		language    *dbinterface.ErrUnexpectedLanguage
+		exists      *dbinterface.ErrUserExists
+		credentials *dbinterface.ErrInvalidCredentials
+		forbidden   *dbinterface.ErrForbidden
+		permission  *dbinterface.ErrInvalidPermission
+		password    *dbinterface.ErrInvalidPassword
+	)
+	switch {
+	case errors.As(err, &withStatus):
+		return withStatus.status
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	case errors.As(err, &language), errors.As(err, &permission), errors.As(err, &password):
+		return http.StatusBadRequest
+	case errors.As(err, &exists):
+		return http.StatusConflict
+	case errors.As(err, &credentials):
+		return http.StatusUnauthorized
+	case errors.As(err, &forbidden):
+		return http.StatusForbidden
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	code := status(err)
+	message := err.Error()
+	if code >= http.StatusInternalServerError {
+		log.Printf("api: %v", err)
+		message = http.StatusText(code)
+	}
+	if code == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	writeJSON(w, code, map[string]string{"error": message})
+}
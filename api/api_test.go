@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The requests here are turned away before the database is used, so the
+// handler needs no connection.
+func TestRejected(t *testing.T) {
+	type args struct {
+		method, path, header, body string
+		wantStatus                 int
+	}
+	tests := map[string]args{
+		"no credentials": {
+			method:     "GET",
+			path:       "/terms",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"not a bearer token": {
+			method:     "GET",
+			path:       "/terms",
+			header:     "Basic dXNlcjpwYXNz",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"undo without credentials": {
+			method:     "POST",
+			path:       "/undo",
+			wantStatus: http.StatusUnauthorized,
+		},
+		"signup turned off": {
+			method:     "POST",
+			path:       "/accounts",
+			body:       `{"name": "ana", "password": "long enough"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		"login with bad body": {
+			method:     "POST",
+			path:       "/login",
+			body:       `{"name":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		"wrong method": {
+			method:     "PATCH",
+			path:       "/terms",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	handler := New(nil, nil, Options{})
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("Got status %d; wanted %d (%s)", w.Code, tt.wantStatus, w.Body)
+			}
+		})
+	}
+}
+
+// Passwords are checked before an account is created, so bad ones are
+// turned away without a connection too.
+func TestSignupPassword(t *testing.T) {
+	handler := New(nil, nil, Options{Signup: true})
+	for name, password := range map[string]string{
+		"too short": "short",
+		"too long":  strings.Repeat("x", 73),
+	} {
+		t.Run(name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"name": "ana", "password": %q}`, password)
+			r := httptest.NewRequest("POST", "/accounts", strings.NewReader(body))
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Got status %d; wanted %d (%s)", w.Code, http.StatusBadRequest, w.Body)
+			}
+		})
+	}
+}
+
+// A request the database does not finish within the timeout is reported as
+// unavailable rather than as an internal error.
+func TestTimeoutStatus(t *testing.T) {
+	err := fmt.Errorf("findTerm %q: %w", "你", context.DeadlineExceeded)
+	if got := status(err); got != http.StatusServiceUnavailable {
+		t.Errorf("Got status %d; wanted %d", got, http.StatusServiceUnavailable)
+	}
+}
@@ -0,0 +1,356 @@
+// Package backup reads and writes archives of a whole study database.
+//
+// An archive is a gzip compressed tar file starting with manifest.json, which
+// records the format version, the schema version of the database and a
+// SHA-256 checksum of each table, followed by one JSON Lines file per table,
+// tables/<name>.jsonl. Only data is stored: rows are JSON objects keyed by
+// column, and the tables are rebuilt from the schema version on restore, so
+// an archive does not depend on the database it was taken from.
+//
+// Archives hold every user's password and token hashes and are not
+// encrypted, so Save makes them readable by their owner only.
+package backup
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FormatVersion is the version of the archive layout written by Write.
+// Version 1 archives also held each table's MySQL CREATE statement, which
+// Read ignores.
+const FormatVersion = 2
+
+const manifestName = "manifest.json"
+
+// maxFileSize is the size of the largest file Read takes from an archive.
+var maxFileSize int64 = 256 << 20
+
+// ErrChecksum is returned by Read when a table does not match the checksum
+// in the manifest.
+var ErrChecksum = errors.New("backup: checksum mismatch")
+
+// Table is the content of one table. Name is the suffix of the table, such
+// as "decks", or empty for the terms table itself.
+type Table struct {
+	Name    string
+	Columns []string
+	Rows    [][]any
+}
+
+// Archive is a backup of every table of a study database.
+type Archive struct {
+	// TableName is the name of the terms table the backup was taken from.
+	TableName     string
+	SchemaVersion int
+	Created       time.Time
+	Tables        []Table
+}
+
+type manifestTable struct {
+	Name     string   `json:"name"`
+	Columns  []string `json:"columns"`
+	Rows     int      `json:"rows"`
+	Checksum string   `json:"sha256"`
+}
+
+type manifest struct {
+	Version       int             `json:"version"`
+	TableName     string          `json:"tableName"`
+	SchemaVersion int             `json:"schemaVersion"`
+	Created       time.Time       `json:"created"`
+	Tables        []manifestTable `json:"tables"`
+}
+
+func tablePath(name string) string {
+	if name == "" {
+		name = "terms"
+	} else {
+		name = "terms_" + name
+	}
+	return "tables/" + name + ".jsonl"
+}
+
+func isTablePath(name string) bool {
+	return strings.HasPrefix(name, "tables/") && strings.HasSuffix(name, ".jsonl")
+}
+
+// Write writes a to w as a compressed archive.
+func Write(w io.Writer, a *Archive) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	m := manifest{
+		Version:       FormatVersion,
+		TableName:     a.TableName,
+		SchemaVersion: a.SchemaVersion,
+		Created:       a.Created,
+	}
+	files := make([][]byte, len(a.Tables))
+	for i, table := range a.Tables {
+		var buf bytes.Buffer
+		for _, row := range table.Rows {
+			if len(row) != len(table.Columns) {
+				return fmt.Errorf("backup: table %q: row has %d values for %d columns", table.Name, len(row), len(table.Columns))
+			}
+			object := make(map[string]any, len(row))
+			for i, value := range row {
+				object[table.Columns[i]] = value
+			}
+			line, err := json.Marshal(object)
+			if err != nil {
+				return fmt.Errorf("backup: table %q: %w", table.Name, err)
+			}
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		files[i] = buf.Bytes()
+		m.Tables = append(m.Tables, manifestTable{
+			Name:     table.Name,
+			Columns:  table.Columns,
+			Rows:     len(table.Rows),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	if err := writeFile(tw, manifestName, data, a.Created); err != nil {
+		return err
+	}
+	for i, table := range a.Tables {
+		if err := writeFile(tw, tablePath(table.Name), files[i], a.Created); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	return nil
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, modified time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: modified}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	return nil
+}
+
+// Read reads an archive written by Write and verifies the checksum of every
+// table. Numbers in rows are json.Number and everything else is a string or
+// nil. Only the tables listed in the manifest are read, and no file may be
+// larger than 256 MiB. Archives written before the manifest came first are
+// read too; their table files are held until the manifest is found.
+func Read(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	defer gz.Close()
+
+	var m *manifest
+	listed := make(map[string]bool)
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("backup: %w", err)
+		}
+		wanted := listed[header.Name]
+		if m == nil {
+			wanted = header.Name == manifestName || isTablePath(header.Name)
+		}
+		if !wanted {
+			continue
+		}
+		data, err := readFile(tr, header)
+		if err != nil {
+			return nil, err
+		}
+		if header.Name != manifestName {
+			files[header.Name] = data
+			continue
+		}
+		m = new(manifest)
+		if err := json.Unmarshal(data, m); err != nil {
+			return nil, fmt.Errorf("backup: manifest: %w", err)
+		}
+		if m.Version < 1 || m.Version > FormatVersion {
+			return nil, fmt.Errorf("backup: unsupported format version %d", m.Version)
+		}
+		for _, mt := range m.Tables {
+			listed[tablePath(mt.Name)] = true
+		}
+		for name := range files {
+			if !listed[name] {
+				delete(files, name)
+			}
+		}
+	}
+	if m == nil {
+		return nil, errors.New("backup: missing manifest")
+	}
+
+	a := &Archive{TableName: m.TableName, SchemaVersion: m.SchemaVersion, Created: m.Created}
+	for _, mt := range m.Tables {
+		data, ok := files[tablePath(mt.Name)]
+		if !ok {
+			return nil, fmt.Errorf("backup: missing table %q", mt.Name)
+		}
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != mt.Checksum {
+			return nil, fmt.Errorf("%w in table %q", ErrChecksum, mt.Name)
+		}
+		table := Table{Name: mt.Name, Columns: mt.Columns}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		scanner.Buffer(nil, len(data)+1)
+		for scanner.Scan() {
+			decoder := json.NewDecoder(bytes.NewReader(scanner.Bytes()))
+			decoder.UseNumber()
+			var object map[string]any
+			if err := decoder.Decode(&object); err != nil {
+				return nil, fmt.Errorf("backup: table %q: %w", mt.Name, err)
+			}
+			row := make([]any, len(mt.Columns))
+			for i, column := range mt.Columns {
+				row[i] = object[column]
+			}
+			table.Rows = append(table.Rows, row)
+		}
+		if len(table.Rows) != mt.Rows {
+			return nil, fmt.Errorf("backup: table %q has %d rows, expected %d", mt.Name, len(table.Rows), mt.Rows)
+		}
+		a.Tables = append(a.Tables, table)
+	}
+	return a, nil
+}
+
+// readFile reads the file at the tar reader's position, which must not be
+// larger than maxFileSize.
+func readFile(tr *tar.Reader, header *tar.Header) ([]byte, error) {
+	if header.Size > maxFileSize {
+		return nil, fmt.Errorf("backup: %s is larger than %d bytes", header.Name, maxFileSize)
+	}
+	data, err := io.ReadAll(io.LimitReader(tr, maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("backup: %w", err)
+	}
+	if int64(len(data)) > maxFileSize {
+		return nil, fmt.Errorf("backup: %s is larger than %d bytes", header.Name, maxFileSize)
+	}
+	return data, nil
+}
+
+// FileName returns the name of a backup taken at t, which sorts in the
+// order the backups were taken. Names are precise to the millisecond, so a
+// backup taken by hand while a scheduled one is saved gets a name of its own.
+func FileName(t time.Time) string {
+	return "flashcards-" + t.UTC().Format("20060102-150405.000") + ".tar.gz"
+}
+
+// Rotate deletes all but the newest keep backups in dir. Only files named
+// by FileName are considered. keep must be at least 1, so the newest backup
+// is never deleted.
+func Rotate(dir string, keep int) error {
+	if keep < 1 {
+		return fmt.Errorf("backup: cannot keep %d backups", keep)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("backup: %w", err)
+	}
+	var backups []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, "flashcards-") && strings.HasSuffix(name, ".tar.gz") {
+			backups = append(backups, name)
+		}
+	}
+	sort.Strings(backups)
+	for len(backups) > keep {
+		if err := os.Remove(filepath.Join(dir, backups[0])); err != nil {
+			return fmt.Errorf("backup: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Save takes a backup with take into a new file in dir named by FileName and
+// returns its path. The file only appears once it is complete, and Save fails
+// rather than replace a backup that already has the name.
+func Save(ctx context.Context, dir string, take func(ctx context.Context, w io.Writer) error) (string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("backup: %w", err)
+	}
+	// CreateTemp makes the file readable by its owner only.
+	file, err := os.CreateTemp(dir, ".flashcards-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("backup: %w", err)
+	}
+	defer os.Remove(file.Name())
+	if err := take(ctx, file); err != nil {
+		file.Close()
+		return "", err
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("backup: %w", err)
+	}
+	path := filepath.Join(dir, FileName(time.Now()))
+	// Unlike a rename, a link fails if path exists.
+	if err := os.Link(file.Name(), path); err != nil {
+		return "", fmt.Errorf("backup: %w", err)
+	}
+	return path, nil
+}
+
+// Schedule saves a backup into dir every interval and rotates the backups
+// there, keeping the newest keep, until ctx is done. Failures are logged and
+// retried at the next interval.
+func Schedule(ctx context.Context, dir string, interval time.Duration, keep int, take func(ctx context.Context, w io.Writer) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		path, err := Save(ctx, dir, take)
+		if err != nil {
+			log.Printf("Scheduled backup failed: %v", err)
+			continue
+		}
+		log.Printf("Saved backup %s", path)
+		if err := Rotate(dir, keep); err != nil {
+			log.Printf("Backup rotation failed: %v", err)
+		}
+	}
+}
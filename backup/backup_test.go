@@ -0,0 +1,197 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func testArchive() *Archive {
+	return &Archive{
+		TableName:     "terms",
+		SchemaVersion: 4,
+		Created:       time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		Tables: []Table{
+			{
+				Columns: []string{"id", "term", "deleted_at"},
+				Rows:    [][]any{{1, "我", nil}, {2, "们", "2024-03-01 12:00:00"}},
+			},
+			{Name: "decks", Columns: []string{"id", "name"}},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testArchive()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	want := testArchive()
+	want.Tables[0].Rows = [][]any{{json.Number("1"), "我", nil}, {json.Number("2"), "们", "2024-03-01 12:00:00"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %+v; wanted %+v", got, want)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testArchive()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tar pads files, so changing a byte of a row keeps the archive readable.
+	data = bytes.Replace(data, []byte(`"id":2`), []byte(`"id":3`), 1)
+	var corrupted bytes.Buffer
+	w := gzip.NewWriter(&corrupted)
+	w.Write(data)
+	w.Close()
+
+	if _, err := Read(&corrupted); !errors.Is(err, ErrChecksum) {
+		t.Errorf("Got error %v; wanted %v", err, ErrChecksum)
+	}
+}
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	var names []string
+	for i := 0; i < 5; i++ {
+		name := FileName(start.Add(time.Duration(i) * time.Hour))
+		names = append(names, name)
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Rotate(dir, 2); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	want := []string{names[3], names[4], "notes.txt"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v; wanted %v", got, want)
+	}
+
+	if err := Rotate(dir, 0); err == nil {
+		t.Errorf("Rotate kept no backups; wanted an error")
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != len(want) {
+		t.Errorf("Got %d files, %v; wanted %d left", len(entries), err, len(want))
+	}
+}
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	take := func(ctx context.Context, w io.Writer) error {
+		return Write(w, testArchive())
+	}
+	// Two backups in the same second both survive.
+	var paths []string
+	for i := 0; i < 2; i++ {
+		path, err := Save(context.Background(), dir, take)
+		if err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		paths = append(paths, path)
+		time.Sleep(2 * time.Millisecond)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if paths[0] == paths[1] || len(entries) != 2 {
+		t.Errorf("Got %v in %d files; wanted two backups", paths, len(entries))
+	}
+}
+
+func TestReadLimits(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testArchive()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	archive := buf.Bytes()
+
+	defer func(size int64) { maxFileSize = size }(maxFileSize)
+	maxFileSize = 16
+	if _, err := Read(bytes.NewReader(archive)); err == nil {
+		t.Errorf("Read a file larger than the limit; wanted an error")
+	}
+}
+
+func TestReadSkipsUnlisted(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, testArchive()); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+
+	// Copy the archive with a file that is not in the manifest and too
+	// large to read.
+	var extended bytes.Buffer
+	w := gzip.NewWriter(&extended)
+	tw := tar.NewWriter(w)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tw.WriteHeader(header)
+		tw.Write(data)
+	}
+	extra := bytes.Repeat([]byte("x"), 64<<10)
+	tw.WriteHeader(&tar.Header{Name: "tables/terms_extra.jsonl", Mode: 0o644, Size: int64(len(extra))})
+	tw.Write(extra)
+	tw.Close()
+	w.Close()
+
+	defer func(size int64) { maxFileSize = size }(maxFileSize)
+	maxFileSize = 4 << 10
+	got, err := Read(&extended)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if len(got.Tables) != len(testArchive().Tables) {
+		t.Errorf("Got %d tables; wanted %d", len(got.Tables), len(testArchive().Tables))
+	}
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/flashcards/dict"
+	"github.com/flashcards/language"
+	"github.com/flashcards/pinyin"
+)
+
+// Config holds the preferences of the current user. It is stored as JSON in
+// the user's config directory so each user keeps their own settings.
+type Config struct {
+	// User is the name of the user whose cards are studied. It defaults to
+	// the user owning the cards from before there were users.
+	User string `json:"user,omitempty"`
+	// Language is the name of the language being studied.
+	Language    string       `json:"language"`
+	PinyinStyle pinyin.Style `json:"pinyinStyle"`
+	// DictionarySources are loaded in priority order, highest first. When
+	// empty, the language's default dictionaries are used.
+	DictionarySources []dict.Source `json:"dictionarySources,omitempty"`
+	// UserDictionary is the file holding the user's own entries. It defaults
+	// to user_dict.tsv next to the config file.
+	UserDictionary string `json:"userDictionary,omitempty"`
+	// HSKVersion is "2.0" or "3.0". HSKLists default to the lists for that
+	// version under dict/hsk.
+	HSKVersion string         `json:"hskVersion"`
+	HSKLists   []dict.HSKList `json:"hskLists,omitempty"`
+	// FrequencyList is a SUBTLEX-CH style word frequency list. It is
+	// optional.
+	FrequencyList   string `json:"frequencyList"`
+	SortByFrequency bool   `json:"sortByFrequency"`
+	// HanziDictionary and HanziGraphics are the Make Me a Hanzi
+	// dictionary.txt and graphics.txt files. They are optional.
+	HanziDictionary string `json:"hanziDictionary"`
+	HanziGraphics   string `json:"hanziGraphics"`
+	// StrokeOrderDir is where stroke order diagrams are written.
+	StrokeOrderDir string `json:"strokeOrderDir"`
+	// SentencesFile holds example sentence pairs. ExampleSentences is how
+	// many are shown with a card, 0 to show none.
+	SentencesFile    string `json:"sentencesFile"`
+	ExampleSentences int    `json:"exampleSentences"`
+	// IgnoreTones makes the typed-answer quiz accept pinyin with wrong or
+	// missing tones.
+	IgnoreTones bool `json:"ignoreTones"`
+	// LeechThreshold is how many failed reviews make a card a leech, 0 to
+	// never flag leeches.
+	LeechThreshold int `json:"leechThreshold"`
+	// BackupDir is where backups are saved. Serve mode saves one every
+	// BackupInterval, a duration such as "24h", and only the newest
+	// BackupKeep are kept.
+	BackupDir      string `json:"backupDir"`
+	BackupInterval string `json:"backupInterval"`
+	BackupKeep     int    `json:"backupKeep"`
+	// ListenAddr is the address serve mode serves the HTTP API on, empty to
+	// not serve it. Signup lets anyone who can reach it create an account.
+	ListenAddr string `json:"listenAddr"`
+	Signup     bool   `json:"signup"`
+	// OperationTimeout is how long the database may take over one
+	// operation, such as adding a card or answering an API request, a
+	// duration such as "30s". Backups and restores are not limited.
+	OperationTimeout string `json:"operationTimeout"`
+}
+
+func Default() Config {
+	return Config{
+		Language:         language.Chinese{}.Name(),
+		PinyinStyle:      pinyin.Marks,
+		HSKVersion:       "2.0",
+		FrequencyList:    "dict/frequency.tsv",
+		HanziDictionary:  "dict/makemeahanzi/dictionary.txt",
+		HanziGraphics:    "dict/makemeahanzi/graphics.txt",
+		StrokeOrderDir:   "stroke-order",
+		SentencesFile:    "dict/sentences.tsv",
+		ExampleSentences: 2,
+		LeechThreshold:   8,
+		BackupDir:        "backups",
+		BackupInterval:   "24h",
+		BackupKeep:       7,
+		ListenAddr:       "127.0.0.1:8080",
+		OperationTimeout: "30s",
+	}
+}
+
+// StudyLanguage returns the configured language.
+func (c Config) StudyLanguage() (language.Language, error) {
+	return language.ByName(c.Language)
+}
+
+// BackupEvery returns how often serve mode saves a backup.
+func (c Config) BackupEvery() (time.Duration, error) {
+	every, err := time.ParseDuration(c.BackupInterval)
+	if err != nil {
+		return 0, err
+	}
+	if every <= 0 {
+		return 0, fmt.Errorf("backup interval %q must be positive", c.BackupInterval)
+	}
+	return every, nil
+}
+
+// Timeout returns how long one operation may take.
+func (c Config) Timeout() (time.Duration, error) {
+	timeout, err := time.ParseDuration(c.OperationTimeout)
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("operation timeout %q must be positive", c.OperationTimeout)
+	}
+	return timeout, nil
+}
+
+// HSK returns the configured HSK word lists.
+func (c Config) HSK() []dict.HSKList {
+	if len(c.HSKLists) > 0 {
+		return c.HSKLists
+	}
+	return dict.DefaultHSKLists(c.HSKVersion)
+}
+
+// Dictionaries returns the configured dictionary sources, falling back to
+// the defaults for lang.
+func (c Config) Dictionaries(lang language.Language) []dict.Source {
+	if len(c.DictionarySources) > 0 {
+		return c.DictionarySources
+	}
+	return lang.Dictionaries()
+}
+
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "flashcards", "config.json"), nil
+}
+
+// Load reads the config at path. A missing file gives the default config.
+func Load(path string) (Config, error) {
+	cfg := Default()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return Default(), err
+	}
+	if _, err := pinyin.ParseStyle(string(cfg.PinyinStyle)); err != nil {
+		return Default(), err
+	}
+	if _, err := cfg.StudyLanguage(); err != nil {
+		return Default(), err
+	}
+	if _, err := cfg.BackupEvery(); err != nil {
+		return Default(), err
+	}
+	if _, err := cfg.Timeout(); err != nil {
+		return Default(), err
+	}
+	if cfg.BackupKeep < 1 {
+		return Default(), fmt.Errorf("backupKeep %d must be at least 1", cfg.BackupKeep)
+	}
+	return cfg, nil
+}
+
+func (c Config) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -1,7 +1,177 @@
+DROP TABLE IF EXISTS terms_grants;
+DROP TABLE IF EXISTS terms_tokens;
+DROP TABLE IF EXISTS terms_users;
+DROP TABLE IF EXISTS terms_history;
+DROP TABLE IF EXISTS terms_journal;
+DROP TABLE IF EXISTS terms_lapses;
+DROP TABLE IF EXISTS terms_review_events;
+DROP TABLE IF EXISTS terms_direction_reviews;
+DROP TABLE IF EXISTS terms_deck_directions;
+DROP TABLE IF EXISTS terms_answers;
+DROP TABLE IF EXISTS terms_cloze_reviews;
+DROP TABLE IF EXISTS terms_clozes;
+DROP TABLE IF EXISTS terms_sentences;
+DROP TABLE IF EXISTS terms_components;
+DROP TABLE IF EXISTS terms_characters;
+DROP TABLE IF EXISTS terms_term_tags;
+DROP TABLE IF EXISTS terms_tags;
+DROP TABLE IF EXISTS terms_deck_terms;
+DROP TABLE IF EXISTS terms_decks;
 DROP TABLE IF EXISTS terms;
 CREATE TABLE terms (
     id INT AUTO_INCREMENT NOT NULL,
+    user_id INT NOT NULL,
     term VARCHAR(128) NOT NULL,
+    language VARCHAR(32) NOT NULL DEFAULT 'Chinese',
     definition VARCHAR(255) NOT NULL,
+    shared BOOLEAN NOT NULL DEFAULT FALSE,
+    deleted_at DATETIME NULL DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `user_term` (`user_id`, `language`, `term`)
+);
+CREATE TABLE terms_users (
+    id INT AUTO_INCREMENT NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    password_hash VARCHAR(255) NULL DEFAULT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY (`name`)
+);
+CREATE TABLE terms_tokens (
+    token_hash CHAR(64) NOT NULL,
+    user_id INT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    permission VARCHAR(8) NOT NULL,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NULL DEFAULT NULL,
+    PRIMARY KEY (`token_hash`),
+    KEY (`user_id`)
+);
+CREATE TABLE terms_grants (
+    owner_id INT NOT NULL,
+    grantee_id INT NOT NULL,
+    permission VARCHAR(8) NOT NULL,
+    PRIMARY KEY (`owner_id`, `grantee_id`)
+);
+CREATE TABLE terms_decks (
+    id INT AUTO_INCREMENT NOT NULL,
+    user_id INT NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    language VARCHAR(32) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `user_name` (`user_id`, `name`)
+);
+CREATE TABLE terms_deck_terms (
+    deck_id INT NOT NULL,
+    term_id INT NOT NULL,
+    PRIMARY KEY (`deck_id`, `term_id`)
+);
+CREATE TABLE terms_tags (
+    id INT AUTO_INCREMENT NOT NULL,
+    user_id INT NOT NULL,
+    name VARCHAR(128) NOT NULL,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `user_name` (`user_id`, `name`)
+);
+CREATE TABLE terms_term_tags (
+    term_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    PRIMARY KEY (`term_id`, `tag_id`)
+);
+CREATE TABLE terms_characters (
+    term_id INT NOT NULL,
+    radical VARCHAR(8) NOT NULL,
+    strokes INT NOT NULL,
+    decomposition VARCHAR(64) NOT NULL,
+    PRIMARY KEY (`term_id`)
+);
+CREATE TABLE terms_components (
+    term_id INT NOT NULL,
+    component VARCHAR(8) NOT NULL,
+    PRIMARY KEY (`term_id`, `component`),
+    KEY (`component`)
+);
+CREATE TABLE terms_sentences (
+    id INT AUTO_INCREMENT NOT NULL,
+    term_id INT NOT NULL,
+    sentence VARCHAR(512) NOT NULL,
+    translation VARCHAR(512) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY (`term_id`)
+);
+CREATE TABLE terms_clozes (
+    id INT AUTO_INCREMENT NOT NULL,
+    term_id INT NOT NULL,
+    sentence VARCHAR(512) NOT NULL,
+    translation VARCHAR(512) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY (`term_id`)
+);
+CREATE TABLE terms_cloze_reviews (
+    id INT AUTO_INCREMENT NOT NULL,
+    cloze_id INT NOT NULL,
+    answer VARCHAR(255) NOT NULL,
+    correct BOOLEAN NOT NULL,
+    reviewed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY (`cloze_id`)
+);
+CREATE TABLE terms_answers (
+    id INT AUTO_INCREMENT NOT NULL,
+    term_id INT NOT NULL,
+    answer VARCHAR(255) NOT NULL,
+    correct BOOLEAN NOT NULL,
+    answered_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY (`term_id`)
+);
+CREATE TABLE terms_deck_directions (
+    deck_id INT NOT NULL,
+    direction VARCHAR(32) NOT NULL,
+    PRIMARY KEY (`deck_id`, `direction`)
+);
+CREATE TABLE terms_direction_reviews (
+    id INT AUTO_INCREMENT NOT NULL,
+    term_id INT NOT NULL,
+    direction VARCHAR(32) NOT NULL,
+    grade TINYINT NOT NULL,
+    reviewed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY (`term_id`, `direction`)
+);
+CREATE TABLE terms_review_events (
+    id INT AUTO_INCREMENT NOT NULL,
+    term_id INT NOT NULL,
+    kind VARCHAR(16) NOT NULL,
+    correct BOOLEAN NOT NULL,
+    happened_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY (`term_id`),
+    KEY (`happened_at`)
+);
+CREATE TABLE terms_lapses (
+    term_id INT NOT NULL,
+    lapses INT NOT NULL,
+    leech BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (`term_id`)
+);
+CREATE TABLE terms_journal (
+    id INT AUTO_INCREMENT NOT NULL,
+    user_id INT NOT NULL,
+    op VARCHAR(16) NOT NULL,
+    data JSON NOT NULL,
+    undone BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
     PRIMARY KEY (`id`)
 );
+CREATE TABLE terms_history (
+    id INT AUTO_INCREMENT NOT NULL,
+    user_id INT NOT NULL,
+    term_id INT NOT NULL,
+    term VARCHAR(128) NOT NULL,
+    kind VARCHAR(32) NOT NULL,
+    detail TEXT NOT NULL,
+    happened_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (`id`),
+    KEY (`user_id`, `term`)
+);
@@ -19,13 +19,10 @@ func CreateTable(ctx context.Context, port, dbName, password, tableName string)
 		return err
 	}
 
-	createTableExec := fmt.Sprintf(`CREATE TABLE %s (
-			id INT AUTO_INCREMENT NOT NULL,
-			term VARCHAR(128) NOT NULL,
-			definition VARCHAR(255) NOT NULL,
-			PRIMARY KEY (id)
-		)`, tableName)
-	_, err = db.ExecContext(ctx, createTableExec)
-
-	return err
+	for _, createTableExec := range Schema(tableName) {
+		if _, err = db.ExecContext(ctx, createTableExec); err != nil {
+			return err
+		}
+	}
+	return nil
 }
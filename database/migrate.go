@@ -0,0 +1,637 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type migration struct {
+	version     int
+	description string
+	apply       func(ctx context.Context, db *sql.DB, tableName string) error
+}
+
+// migrations upgrade databases created by older versions, oldest first. They
+// must never renumber terms, since ids are referenced from outside the
+// database.
+var migrations = []migration{
+	{1, "stable term ids", stableIds},
+	{2, "unique terms", uniqueTerms},
+	{3, "operation journal", journal},
+	{4, "card history", history},
+	{5, "users", users},
+	{6, "accounts", accounts},
+	{7, "leech flag", leechFlag},
+	{8, "longer history details", historyDetail},
+	{9, "card languages", cardLanguages},
+}
+
+// LatestVersion is the schema version Migrate brings databases to.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// lockTimeout is how many seconds Migrate waits for another client to
+// finish migrating.
+const lockTimeout = 60
+
+// Migrate applies the migrations that have not been applied to the terms
+// table called tableName yet. The applied versions are recorded in the
+// tableName_migrations table, so each migration runs once. Clients migrating
+// the same tables at once take turns: the first applies the migrations while
+// the others wait for it, then find nothing left to do.
+func Migrate(ctx context.Context, db *sql.DB, tableName string) error {
+	// A named lock belongs to the session that took it, so it is taken and
+	// released on a connection of its own.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("Migrate: %v", err)
+	}
+	defer conn.Close()
+	lock := tableName + "_migrations"
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", lock, lockTimeout).Scan(&locked); err != nil {
+		return fmt.Errorf("Migrate: %v", err)
+	}
+	if locked.Int64 != 1 {
+		return fmt.Errorf("Migrate: timed out waiting for another client to migrate %s", tableName)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT RELEASE_LOCK(?)", lock)
+
+	if err := createMigrationsTable(ctx, db, tableName); err != nil {
+		return fmt.Errorf("Migrate: %v", err)
+	}
+	var current int
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s_migrations", tableName)
+	if err := db.QueryRowContext(ctx, query).Scan(&current); err != nil {
+		return fmt.Errorf("Migrate: %v", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := m.apply(ctx, db, tableName); err != nil {
+			return fmt.Errorf("Migrate to version %d (%s): %v", m.version, m.description, err)
+		}
+		// A client that did not wait for the lock, such as an older
+		// version of the app, may have recorded the version already.
+		exec := fmt.Sprintf("INSERT IGNORE INTO %s_migrations (version) VALUES (?)", tableName)
+		if _, err := db.ExecContext(ctx, exec, m.version); err != nil {
+			return fmt.Errorf("Migrate: %v", err)
+		}
+	}
+	return nil
+}
+
+// CreateAt creates the tables of the current schema for the terms table
+// called tableName and records the migrations up to version as applied. It
+// is used to load data taken at that version, which Migrate then brings up
+// to date; the migrations also work on tables that already have the current
+// shape.
+func CreateAt(ctx context.Context, db *sql.DB, tableName string, version int) error {
+	if err := createTables(ctx, db, tableName); err != nil {
+		return fmt.Errorf("CreateAt: %v", err)
+	}
+	if err := createMigrationsTable(ctx, db, tableName); err != nil {
+		return fmt.Errorf("CreateAt: %v", err)
+	}
+	exec := fmt.Sprintf("INSERT IGNORE INTO %s_migrations (version) VALUES (?)", tableName)
+	for _, m := range migrations {
+		if m.version > version {
+			break
+		}
+		if _, err := db.ExecContext(ctx, exec, m.version); err != nil {
+			return fmt.Errorf("CreateAt: %v", err)
+		}
+	}
+	return nil
+}
+
+func createMigrationsTable(ctx context.Context, db *sql.DB, tableName string) error {
+	exec := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s_migrations (
+		version INT NOT NULL,
+		applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (version)
+	)`, tableName)
+	_, err := db.ExecContext(ctx, exec)
+	return err
+}
+
+// stableIds moves a database from reusing the ids of deleted terms to plain
+// auto-increment ids. The tables of the schema at this version that do not
+// exist yet are created, the deleted_at column that replaces hard deletes is
+// added, and the auto-increment counter is moved past the highest id in use.
+// Existing ids are kept as they are.
+func stableIds(ctx context.Context, db *sql.DB, tableName string) error {
+	err := createIfMissing(ctx, db,
+		fmt.Sprintf(`CREATE TABLE %s (
+			id INT AUTO_INCREMENT NOT NULL,
+			term VARCHAR(128) NOT NULL,
+			definition VARCHAR(255) NOT NULL,
+			deleted_at DATETIME NULL DEFAULT NULL,
+			PRIMARY KEY (id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_decks (
+			id INT AUTO_INCREMENT NOT NULL,
+			name VARCHAR(128) NOT NULL,
+			language VARCHAR(32) NOT NULL,
+			PRIMARY KEY (id),
+			UNIQUE KEY (name)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_deck_terms (
+			deck_id INT NOT NULL,
+			term_id INT NOT NULL,
+			PRIMARY KEY (deck_id, term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_tags (
+			id INT AUTO_INCREMENT NOT NULL,
+			name VARCHAR(128) NOT NULL,
+			PRIMARY KEY (id),
+			UNIQUE KEY (name)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_term_tags (
+			term_id INT NOT NULL,
+			tag_id INT NOT NULL,
+			PRIMARY KEY (term_id, tag_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_characters (
+			term_id INT NOT NULL,
+			radical VARCHAR(8) NOT NULL,
+			strokes INT NOT NULL,
+			decomposition VARCHAR(64) NOT NULL,
+			PRIMARY KEY (term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_components (
+			term_id INT NOT NULL,
+			component VARCHAR(8) NOT NULL,
+			PRIMARY KEY (term_id, component),
+			KEY (component)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_sentences (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			sentence VARCHAR(512) NOT NULL,
+			translation VARCHAR(512) NOT NULL,
+			PRIMARY KEY (id),
+			KEY (term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_clozes (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			sentence VARCHAR(512) NOT NULL,
+			translation VARCHAR(512) NOT NULL,
+			PRIMARY KEY (id),
+			KEY (term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_cloze_reviews (
+			id INT AUTO_INCREMENT NOT NULL,
+			cloze_id INT NOT NULL,
+			answer VARCHAR(255) NOT NULL,
+			correct BOOLEAN NOT NULL,
+			reviewed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY (cloze_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_answers (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			answer VARCHAR(255) NOT NULL,
+			correct BOOLEAN NOT NULL,
+			answered_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY (term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_deck_directions (
+			deck_id INT NOT NULL,
+			direction VARCHAR(32) NOT NULL,
+			PRIMARY KEY (deck_id, direction)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_direction_reviews (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			direction VARCHAR(32) NOT NULL,
+			grade TINYINT NOT NULL,
+			reviewed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY (term_id, direction)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_review_events (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			kind VARCHAR(16) NOT NULL,
+			correct BOOLEAN NOT NULL,
+			happened_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY (term_id),
+			KEY (happened_at)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_lapses (
+			term_id INT NOT NULL,
+			lapses INT NOT NULL,
+			PRIMARY KEY (term_id)
+		)`, tableName),
+	)
+	if err != nil {
+		return err
+	}
+
+	var columns int
+	query := `SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'deleted_at'`
+	if err := db.QueryRowContext(ctx, query, tableName).Scan(&columns); err != nil {
+		return err
+	}
+	if columns == 0 {
+		exec := fmt.Sprintf("ALTER TABLE %s ADD COLUMN deleted_at DATETIME NULL DEFAULT NULL", tableName)
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			return err
+		}
+	}
+
+	var next int64
+	query = fmt.Sprintf("SELECT COALESCE(MAX(id), 0) + 1 FROM %s", tableName)
+	if err := db.QueryRowContext(ctx, query).Scan(&next); err != nil {
+		return err
+	}
+	exec := fmt.Sprintf("ALTER TABLE %s AUTO_INCREMENT = %d", tableName, next)
+	_, err = db.ExecContext(ctx, exec)
+	return err
+}
+
+// uniqueTerms merges terms that were added more than once into the copy
+// with the lowest id, moving what is linked to the other copies over to it,
+// and then adds a unique key on term. Tables that already have owners or
+// languages, such as those of a backup being restored, only have copies
+// merged within the same user and language, which is what their unique key
+// covers.
+func uniqueTerms(ctx context.Context, db *sql.DB, tableName string) error {
+	key := []string{"term"}
+	for _, column := range []string{"language", "user_id"} {
+		ok, err := hasColumn(ctx, db, tableName, column)
+		if err != nil {
+			return err
+		}
+		if ok {
+			key = append([]string{column}, key...)
+		}
+	}
+	var same []string
+	for _, column := range key {
+		same = append(same, fmt.Sprintf("k.%[1]s = t.%[1]s", column))
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf(`SELECT t.id, k.id FROM %[1]s t
+		JOIN (SELECT %[2]s, MIN(id) AS id FROM %[1]s GROUP BY %[2]s) k ON %[3]s
+		WHERE t.id <> k.id`, tableName, strings.Join(key, ", "), strings.Join(same, " AND "))
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	duplicates := make(map[int64]int64)
+	for rows.Next() {
+		var id, keep int64
+		if err := rows.Scan(&id, &keep); err != nil {
+			rows.Close()
+			return err
+		}
+		duplicates[id] = keep
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, keep := range duplicates {
+		for _, suffix := range TermLinks {
+			table := tableName + "_" + suffix
+			// Rows the kept copy already has are skipped by IGNORE and
+			// deleted with the duplicate.
+			exec := fmt.Sprintf("UPDATE IGNORE %s SET term_id = ? WHERE term_id = ?", table)
+			if _, err := tx.ExecContext(ctx, exec, keep, id); err != nil {
+				return err
+			}
+			exec = fmt.Sprintf("DELETE FROM %s WHERE term_id = ?", table)
+			if _, err := tx.ExecContext(ctx, exec, id); err != nil {
+				return err
+			}
+		}
+		exec := fmt.Sprintf("DELETE FROM %s WHERE id = ?", tableName)
+		if _, err := tx.ExecContext(ctx, exec, id); err != nil {
+			return err
+		}
+	}
+
+	var keys int
+	query = `SELECT COUNT(*) FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'term' AND NON_UNIQUE = 0`
+	if err := tx.QueryRowContext(ctx, query, tableName).Scan(&keys); err != nil {
+		return err
+	}
+	if keys == 0 {
+		exec := fmt.Sprintf("ALTER TABLE %s ADD UNIQUE KEY (term)", tableName)
+		if _, err := tx.ExecContext(ctx, exec); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// journal creates the table recording operations so they can be undone.
+func journal(ctx context.Context, db *sql.DB, tableName string) error {
+	return createIfMissing(ctx, db, fmt.Sprintf(`CREATE TABLE %s_journal (
+		id INT AUTO_INCREMENT NOT NULL,
+		op VARCHAR(16) NOT NULL,
+		data JSON NOT NULL,
+		undone BOOLEAN NOT NULL DEFAULT FALSE,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (id)
+	)`, tableName))
+}
+
+// history creates the table holding what happened to every card.
+func history(ctx context.Context, db *sql.DB, tableName string) error {
+	return createIfMissing(ctx, db, fmt.Sprintf(`CREATE TABLE %s_history (
+		id INT AUTO_INCREMENT NOT NULL,
+		term_id INT NOT NULL,
+		term VARCHAR(128) NOT NULL,
+		kind VARCHAR(32) NOT NULL,
+		detail VARCHAR(512) NOT NULL,
+		happened_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (id),
+		KEY (term)
+	)`, tableName))
+}
+
+// DefaultUserId is the user that owns the cards of databases created before
+// there were users.
+const DefaultUserId = 1
+
+// users gives every card, deck, tag, journal entry and history event an
+// owner, DefaultUserId for existing ones, and makes terms, deck names and
+// tag names unique per user rather than in the whole table.
+func users(ctx context.Context, db *sql.DB, tableName string) error {
+	err := createIfMissing(ctx, db, fmt.Sprintf(`CREATE TABLE %s_users (
+		id INT AUTO_INCREMENT NOT NULL,
+		name VARCHAR(64) NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (id),
+		UNIQUE KEY (name)
+	)`, tableName))
+	if err != nil {
+		return err
+	}
+	exec := fmt.Sprintf("INSERT IGNORE INTO %s_users (id, name) VALUES (?, 'default')", tableName)
+	if _, err := db.ExecContext(ctx, exec, DefaultUserId); err != nil {
+		return err
+	}
+
+	owned := []string{tableName, tableName + "_decks", tableName + "_tags", tableName + "_journal", tableName + "_history"}
+	for _, table := range owned {
+		ok, err := hasColumn(ctx, db, table, "user_id")
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		exec := fmt.Sprintf("ALTER TABLE %s ADD COLUMN user_id INT NOT NULL DEFAULT %d AFTER id", table, DefaultUserId)
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			return err
+		}
+		exec = fmt.Sprintf("ALTER TABLE %s ALTER COLUMN user_id DROP DEFAULT", table)
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			return err
+		}
+	}
+	ok, err := hasColumn(ctx, db, tableName, "shared")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		exec := fmt.Sprintf("ALTER TABLE %s ADD COLUMN shared BOOLEAN NOT NULL DEFAULT FALSE AFTER definition", tableName)
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			return err
+		}
+	}
+
+	unique := map[string]string{tableName: "term", tableName + "_decks": "name", tableName + "_tags": "name"}
+	for table, column := range unique {
+		var keys int
+		query := `SELECT COUNT(*) FROM information_schema.STATISTICS
+			WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = ?`
+		if err := db.QueryRowContext(ctx, query, table, column).Scan(&keys); err != nil {
+			return err
+		}
+		if keys == 0 {
+			continue
+		}
+		exec := fmt.Sprintf("ALTER TABLE %[1]s DROP INDEX %[2]s, ADD UNIQUE KEY user_%[2]s (user_id, %[2]s)", table, column)
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			return err
+		}
+	}
+
+	// History is looked up by user and term.
+	var keys int
+	query := `SELECT COUNT(*) FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = 'term'`
+	if err := db.QueryRowContext(ctx, query, tableName+"_history").Scan(&keys); err != nil {
+		return err
+	}
+	if keys == 0 {
+		return nil
+	}
+	exec = fmt.Sprintf("ALTER TABLE %s_history DROP INDEX term, ADD KEY (user_id, term)", tableName)
+	_, err = db.ExecContext(ctx, exec)
+	return err
+}
+
+// accounts adds passwords to users and the tables holding their login
+// sessions, API tokens and the permissions they give each other.
+func accounts(ctx context.Context, db *sql.DB, tableName string) error {
+	err := createIfMissing(ctx, db,
+		fmt.Sprintf(`CREATE TABLE %s_tokens (
+			token_hash CHAR(64) NOT NULL,
+			user_id INT NOT NULL,
+			kind VARCHAR(16) NOT NULL,
+			permission VARCHAR(8) NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NULL DEFAULT NULL,
+			PRIMARY KEY (token_hash),
+			KEY (user_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_grants (
+			owner_id INT NOT NULL,
+			grantee_id INT NOT NULL,
+			permission VARCHAR(8) NOT NULL,
+			PRIMARY KEY (owner_id, grantee_id)
+		)`, tableName),
+	)
+	if err != nil {
+		return err
+	}
+	ok, err := hasColumn(ctx, db, tableName+"_users", "password_hash")
+	if err != nil || ok {
+		return err
+	}
+	exec := fmt.Sprintf("ALTER TABLE %s_users ADD COLUMN password_hash VARCHAR(255) NULL DEFAULT NULL AFTER name", tableName)
+	_, err = db.ExecContext(ctx, exec)
+	return err
+}
+
+// leechFlag moves leeches from a tag named leech to a flag next to the lapse
+// count. A tag of the user's own is named the same, so the history tells them
+// apart: the app recorded its tags as "leech after n lapses" and the user's
+// as plain "leech". Cards the user tagged keep their tag and are not flagged.
+// The app's tags are replaced by the flag, except on cards tagged before there
+// was a history, which are flagged if they have lapses but keep the tag.
+func leechFlag(ctx context.Context, db *sql.DB, tableName string) error {
+	ok, err := hasColumn(ctx, db, tableName+"_lapses", "leech")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		exec := fmt.Sprintf("ALTER TABLE %s_lapses ADD COLUMN leech BOOLEAN NOT NULL DEFAULT FALSE", tableName)
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	userTagged := fmt.Sprintf(`EXISTS (SELECT 1 FROM %s_history h
+		WHERE h.term_id = tt.term_id AND h.kind = 'tagged' AND h.detail = 'leech')`, tableName)
+	appTagged := fmt.Sprintf(`EXISTS (SELECT 1 FROM %s_history h
+		WHERE h.term_id = tt.term_id AND h.kind = 'tagged' AND h.detail LIKE 'leech after %% lapses')`, tableName)
+	exec := fmt.Sprintf(`UPDATE %[1]s_lapses l
+		JOIN %[1]s_term_tags tt ON tt.term_id = l.term_id
+		JOIN %[1]s_tags g ON g.id = tt.tag_id
+		SET l.leech = TRUE WHERE g.name = 'leech' AND NOT %[2]s`, tableName, userTagged)
+	if _, err := tx.ExecContext(ctx, exec); err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`SELECT tt.term_id, tt.tag_id FROM %[1]s_term_tags tt
+		JOIN %[1]s_tags g ON g.id = tt.tag_id
+		WHERE g.name = 'leech' AND %[2]s AND NOT %[3]s`, tableName, appTagged, userTagged)
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	type link struct{ termId, tagId int64 }
+	var links []link
+	for rows.Next() {
+		var l link
+		if err := rows.Scan(&l.termId, &l.tagId); err != nil {
+			rows.Close()
+			return err
+		}
+		links = append(links, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tagIds := make(map[int64]bool)
+	exec = fmt.Sprintf("DELETE FROM %s_term_tags WHERE term_id = ? AND tag_id = ?", tableName)
+	for _, l := range links {
+		if _, err := tx.ExecContext(ctx, exec, l.termId, l.tagId); err != nil {
+			return err
+		}
+		tagIds[l.tagId] = true
+	}
+	// A tag is only dropped once none of the cards it was on keeps it.
+	exec = fmt.Sprintf(`DELETE FROM %[1]s_tags WHERE id = ?
+		AND NOT EXISTS (SELECT 1 FROM %[1]s_term_tags WHERE tag_id = ?)`, tableName)
+	for tagId := range tagIds {
+		if _, err := tx.ExecContext(ctx, exec, tagId, tagId); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// historyDetail makes history details TEXT, since an edit's detail holds
+// two definitions and does not fit VARCHAR(512).
+func historyDetail(ctx context.Context, db *sql.DB, tableName string) error {
+	exec := fmt.Sprintf("ALTER TABLE %s_history MODIFY detail TEXT NOT NULL", tableName)
+	_, err := db.ExecContext(ctx, exec)
+	return err
+}
+
+// cardLanguages records the language of every card, so that cards are found
+// and checked against their own language rather than the one the app is set
+// to, and makes terms unique per user and language. Existing cards with kana
+// are Japanese and the rest Chinese, the only language before there was a
+// choice; a Japanese card written in kanji alone is taken for Chinese.
+func cardLanguages(ctx context.Context, db *sql.DB, tableName string) error {
+	ok, err := hasColumn(ctx, db, tableName, "language")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		exec := fmt.Sprintf("ALTER TABLE %s ADD COLUMN language VARCHAR(32) NOT NULL DEFAULT 'Chinese' AFTER term", tableName)
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			return err
+		}
+	}
+	exec := fmt.Sprintf("UPDATE %s SET language = 'Japanese' WHERE language = 'Chinese' AND term REGEXP '[ぁ-ヿ]'", tableName)
+	if _, err := db.ExecContext(ctx, exec); err != nil {
+		return err
+	}
+
+	var keys int
+	query := `SELECT COUNT(*) FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME = 'user_term' AND COLUMN_NAME = 'language'`
+	if err := db.QueryRowContext(ctx, query, tableName).Scan(&keys); err != nil {
+		return err
+	}
+	if keys > 0 {
+		return nil
+	}
+	exec = fmt.Sprintf("ALTER TABLE %s DROP INDEX user_term, ADD UNIQUE KEY user_term (user_id, language, term)", tableName)
+	_, err = db.ExecContext(ctx, exec)
+	return err
+}
+
+func hasColumn(ctx context.Context, db *sql.DB, table, column string) (bool, error) {
+	var columns int
+	query := `SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`
+	if err := db.QueryRowContext(ctx, query, table, column).Scan(&columns); err != nil {
+		return false, err
+	}
+	return columns > 0, nil
+}
+
+// createTables creates the tables of the current schema that do not exist
+// yet. Migrations create their tables with createIfMissing instead, as the
+// schema was at their version, so later migrations find what they expect.
+func createTables(ctx context.Context, db *sql.DB, tableName string) error {
+	return createIfMissing(ctx, db, Schema(tableName)...)
+}
+
+// createIfMissing runs CREATE TABLE statements for the tables that do not
+// exist yet.
+func createIfMissing(ctx context.Context, db *sql.DB, execs ...string) error {
+	for _, exec := range execs {
+		exec = strings.Replace(exec, "CREATE TABLE", "CREATE TABLE IF NOT EXISTS", 1)
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
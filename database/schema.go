@@ -0,0 +1,195 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TermLinks are the suffixes of the tables whose rows belong to a term
+// through a term_id column.
+var TermLinks = []string{
+	"clozes",
+	"answers",
+	"direction_reviews",
+	"review_events",
+	"lapses",
+	"deck_terms",
+	"term_tags",
+	"characters",
+	"components",
+	"sentences",
+}
+
+// Schema returns the statements creating the terms table called tableName
+// and the tables that hang off it, which are named with tableName as prefix.
+func Schema(tableName string) []string {
+	return []string{
+		fmt.Sprintf(`CREATE TABLE %s (
+			id INT AUTO_INCREMENT NOT NULL,
+			user_id INT NOT NULL,
+			term VARCHAR(128) NOT NULL,
+			language VARCHAR(32) NOT NULL DEFAULT 'Chinese',
+			definition VARCHAR(255) NOT NULL,
+			shared BOOLEAN NOT NULL DEFAULT FALSE,
+			deleted_at DATETIME NULL DEFAULT NULL,
+			PRIMARY KEY (id),
+			UNIQUE KEY user_term (user_id, language, term)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_users (
+			id INT AUTO_INCREMENT NOT NULL,
+			name VARCHAR(64) NOT NULL,
+			password_hash VARCHAR(255) NULL DEFAULT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			UNIQUE KEY (name)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_tokens (
+			token_hash CHAR(64) NOT NULL,
+			user_id INT NOT NULL,
+			kind VARCHAR(16) NOT NULL,
+			permission VARCHAR(8) NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME NULL DEFAULT NULL,
+			PRIMARY KEY (token_hash),
+			KEY (user_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_grants (
+			owner_id INT NOT NULL,
+			grantee_id INT NOT NULL,
+			permission VARCHAR(8) NOT NULL,
+			PRIMARY KEY (owner_id, grantee_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_decks (
+			id INT AUTO_INCREMENT NOT NULL,
+			user_id INT NOT NULL,
+			name VARCHAR(128) NOT NULL,
+			language VARCHAR(32) NOT NULL,
+			PRIMARY KEY (id),
+			UNIQUE KEY user_name (user_id, name)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_deck_terms (
+			deck_id INT NOT NULL,
+			term_id INT NOT NULL,
+			PRIMARY KEY (deck_id, term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_tags (
+			id INT AUTO_INCREMENT NOT NULL,
+			user_id INT NOT NULL,
+			name VARCHAR(128) NOT NULL,
+			PRIMARY KEY (id),
+			UNIQUE KEY user_name (user_id, name)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_term_tags (
+			term_id INT NOT NULL,
+			tag_id INT NOT NULL,
+			PRIMARY KEY (term_id, tag_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_characters (
+			term_id INT NOT NULL,
+			radical VARCHAR(8) NOT NULL,
+			strokes INT NOT NULL,
+			decomposition VARCHAR(64) NOT NULL,
+			PRIMARY KEY (term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_components (
+			term_id INT NOT NULL,
+			component VARCHAR(8) NOT NULL,
+			PRIMARY KEY (term_id, component),
+			KEY (component)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_sentences (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			sentence VARCHAR(512) NOT NULL,
+			translation VARCHAR(512) NOT NULL,
+			PRIMARY KEY (id),
+			KEY (term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_clozes (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			sentence VARCHAR(512) NOT NULL,
+			translation VARCHAR(512) NOT NULL,
+			PRIMARY KEY (id),
+			KEY (term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_cloze_reviews (
+			id INT AUTO_INCREMENT NOT NULL,
+			cloze_id INT NOT NULL,
+			answer VARCHAR(255) NOT NULL,
+			correct BOOLEAN NOT NULL,
+			reviewed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY (cloze_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_answers (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			answer VARCHAR(255) NOT NULL,
+			correct BOOLEAN NOT NULL,
+			answered_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY (term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_deck_directions (
+			deck_id INT NOT NULL,
+			direction VARCHAR(32) NOT NULL,
+			PRIMARY KEY (deck_id, direction)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_direction_reviews (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			direction VARCHAR(32) NOT NULL,
+			grade TINYINT NOT NULL,
+			reviewed_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY (term_id, direction)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_review_events (
+			id INT AUTO_INCREMENT NOT NULL,
+			term_id INT NOT NULL,
+			kind VARCHAR(16) NOT NULL,
+			correct BOOLEAN NOT NULL,
+			happened_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY (term_id),
+			KEY (happened_at)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_lapses (
+			term_id INT NOT NULL,
+			lapses INT NOT NULL,
+			leech BOOLEAN NOT NULL DEFAULT FALSE,
+			PRIMARY KEY (term_id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_journal (
+			id INT AUTO_INCREMENT NOT NULL,
+			user_id INT NOT NULL,
+			op VARCHAR(16) NOT NULL,
+			data JSON NOT NULL,
+			undone BOOLEAN NOT NULL DEFAULT FALSE,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id)
+		)`, tableName),
+		fmt.Sprintf(`CREATE TABLE %s_history (
+			id INT AUTO_INCREMENT NOT NULL,
+			user_id INT NOT NULL,
+			term_id INT NOT NULL,
+			term VARCHAR(128) NOT NULL,
+			kind VARCHAR(32) NOT NULL,
+			detail TEXT NOT NULL,
+			happened_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY (user_id, term)
+		)`, tableName),
+	}
+}
+
+// Tables returns the names of the tables Schema creates for tableName and
+// of the migrations table, in the order Schema creates them.
+func Tables(tableName string) []string {
+	var names []string
+	for _, exec := range Schema(tableName) {
+		names = append(names, strings.Fields(exec)[2])
+	}
+	return append(names, tableName+"_migrations")
+}
@@ -0,0 +1,214 @@
+package dbinterface
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Permission is what a user may do with cards.
+type Permission string
+
+const (
+	Read  Permission = "read"
+	Write Permission = "write"
+)
+
+// ParsePermission parses "read" or "write".
+func ParsePermission(s string) (Permission, error) {
+	switch p := Permission(s); p {
+	case Read, Write:
+		return p, nil
+	}
+	return "", &ErrInvalidPermission{permission: s}
+}
+
+// Allows reports whether p includes need. Write includes Read.
+func (p Permission) Allows(need Permission) bool {
+	return p == Write || (p == Read && need == Read)
+}
+
+// TokenKind tells login sessions and API tokens apart, so that one cannot
+// be used as the other.
+type TokenKind string
+
+const (
+	SessionToken TokenKind = "session"
+	APIToken     TokenKind = "api"
+)
+
+// MinPasswordLength and MaxPasswordLength are the lengths in bytes of the
+// shortest and longest passwords accepted. bcrypt does not take passwords
+// longer than 72 bytes.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// dummyHash is compared against when a user does not exist, so that Login
+// takes as long for unknown users as for wrong passwords.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("flashcards"), bcrypt.DefaultCost)
+
+// CreateAccount creates a user who can log in with password and returns its
+// id.
+func CreateAccount(ctx context.Context, dbc *DatabaseConn, name, password string) (int64, error) {
+	if err := checkPassword(password); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		var err error
+		if id, err = CreateUser(ctx, tx, name); err != nil {
+			return err
+		}
+		return SetPassword(ctx, tx.asUser(id), password)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// SetPassword sets the password of the connection's user, which lets it log
+// in.
+func SetPassword(ctx context.Context, dbc *DatabaseConn, password string) error {
+	if err := checkPassword(password); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("SetPassword: %w", err)
+	}
+	return dbc.setPasswordHash(ctx, hash)
+}
+
+func checkPassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return &ErrInvalidPassword{reason: fmt.Sprintf("it must have at least %d characters", MinPasswordLength)}
+	}
+	if len(password) > MaxPasswordLength {
+		return &ErrInvalidPassword{reason: fmt.Sprintf("it must have at most %d bytes", MaxPasswordLength)}
+	}
+	return nil
+}
+
+// Login checks a user's password and returns a connection for the user.
+// Since every login adds a session, it also deletes expired ones.
+func Login(ctx context.Context, dbc *DatabaseConn, name, password string) (*DatabaseConn, error) {
+	id, hash, err := dbc.passwordHash(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if hash == nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return nil, &ErrInvalidCredentials{}
+	}
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return nil, &ErrInvalidCredentials{}
+	}
+	if err := dbc.deleteExpiredTokens(ctx); err != nil {
+		return nil, err
+	}
+	return dbc.asUser(id), nil
+}
+
+// CreateToken returns a new random token for the connection's user, which
+// Authenticate accepts until it expires after ttl, or until it is revoked if
+// ttl is 0. Only a hash of it is stored.
+func CreateToken(ctx context.Context, dbc *DatabaseConn, kind TokenKind, permission Permission, ttl time.Duration) (string, error) {
+	if _, err := ParsePermission(string(permission)); err != nil {
+		return "", err
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("CreateToken: %w", err)
+	}
+	token := hex.EncodeToString(secret)
+	var expires *time.Time
+	if ttl > 0 {
+		t := time.Now().Add(ttl)
+		expires = &t
+	}
+	if err := dbc.addToken(ctx, hashToken(token), kind, permission, expires); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// Authenticate returns a connection for the user a token of the given kind
+// belongs to and the permission the token gives.
+func Authenticate(ctx context.Context, dbc *DatabaseConn, token string, kind TokenKind) (*DatabaseConn, Permission, error) {
+	userId, permission, err := dbc.findToken(ctx, hashToken(token), kind)
+	if err != nil {
+		return nil, "", err
+	}
+	if userId == 0 {
+		return nil, "", &ErrInvalidCredentials{}
+	}
+	return dbc.asUser(userId), permission, nil
+}
+
+// RevokeToken makes one of the connection user's tokens invalid.
+func RevokeToken(ctx context.Context, dbc *DatabaseConn, token string) error {
+	num, err := dbc.deleteToken(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		return &ErrInvalidCredentials{}
+	}
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Grant lets the user called grantee read, or read and change, the cards of
+// the connection's user.
+func Grant(ctx context.Context, dbc *DatabaseConn, grantee string, permission Permission) error {
+	if _, err := ParsePermission(string(permission)); err != nil {
+		return err
+	}
+	granteeId, err := dbc.findUser(ctx, grantee)
+	if err != nil {
+		return err
+	}
+	return dbc.setGrant(ctx, granteeId, permission)
+}
+
+// RevokeGrant takes back what Grant gave grantee.
+func RevokeGrant(ctx context.Context, dbc *DatabaseConn, grantee string) error {
+	granteeId, err := dbc.findUser(ctx, grantee)
+	if err != nil {
+		return err
+	}
+	return dbc.deleteGrant(ctx, granteeId)
+}
+
+// AsOwner returns a connection for the cards of the user called owner, if
+// owner granted the connection's user need. Users have every permission on
+// their own cards.
+func AsOwner(ctx context.Context, dbc *DatabaseConn, owner string, need Permission) (*DatabaseConn, error) {
+	ownerId, err := dbc.findUser(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	if ownerId == dbc.userId {
+		return dbc, nil
+	}
+	permission, err := dbc.grant(ctx, ownerId)
+	if err != nil {
+		return nil, err
+	}
+	if !permission.Allows(need) {
+		return nil, &ErrForbidden{owner: owner, permission: need}
+	}
+	return dbc.asUser(ownerId), nil
+}
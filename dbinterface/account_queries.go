@@ -0,0 +1,112 @@
+package dbinterface
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func (dbc *DatabaseConn) setPasswordHash(ctx context.Context, hash []byte) error {
+	exec := fmt.Sprintf("UPDATE %s SET password_hash = ? WHERE id = ?", dbc.table("users"))
+	if _, err := dbc.db.ExecContext(ctx, exec, hash, dbc.userId); err != nil {
+		return fmt.Errorf("setPasswordHash: %w", err)
+	}
+	return nil
+}
+
+// passwordHash returns the id and password hash of a user, or a nil hash if
+// the user does not exist or has no password.
+func (dbc *DatabaseConn) passwordHash(ctx context.Context, name string) (int64, []byte, error) {
+	var id int64
+	var hash []byte
+	query := fmt.Sprintf("SELECT id, password_hash FROM %s WHERE name = ?", dbc.table("users"))
+	err := dbc.db.QueryRowContext(ctx, query, name).Scan(&id, &hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil, nil
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("passwordHash: %w", err)
+	}
+	return id, hash, nil
+}
+
+func (dbc *DatabaseConn) addToken(ctx context.Context, tokenHash string, kind TokenKind, permission Permission, expires *time.Time) error {
+	exec := fmt.Sprintf("INSERT INTO %s (token_hash, user_id, kind, permission, expires_at) VALUES (?, ?, ?, ?, ?)", dbc.table("tokens"))
+	if _, err := dbc.db.ExecContext(ctx, exec, tokenHash, dbc.userId, kind, permission, expires); err != nil {
+		return fmt.Errorf("addToken: %w", err)
+	}
+	return nil
+}
+
+// findToken returns the user and permission of a token of the given kind
+// that has not expired, or 0 if there is none.
+func (dbc *DatabaseConn) findToken(ctx context.Context, tokenHash string, kind TokenKind) (int64, Permission, error) {
+	var userId int64
+	var permission Permission
+	query := fmt.Sprintf(`SELECT user_id, permission FROM %s
+		WHERE token_hash = ? AND kind = ? AND (expires_at IS NULL OR expires_at > ?)`, dbc.table("tokens"))
+	err := dbc.db.QueryRowContext(ctx, query, tokenHash, kind, time.Now()).Scan(&userId, &permission)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("findToken: %w", err)
+	}
+	return userId, permission, nil
+}
+
+// deleteExpiredTokens deletes the tokens of every user that have expired.
+func (dbc *DatabaseConn) deleteExpiredTokens(ctx context.Context) error {
+	exec := fmt.Sprintf("DELETE FROM %s WHERE expires_at <= ?", dbc.table("tokens"))
+	if _, err := dbc.db.ExecContext(ctx, exec, time.Now()); err != nil {
+		return fmt.Errorf("deleteExpiredTokens: %w", err)
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) deleteToken(ctx context.Context, tokenHash string) (int64, error) {
+	exec := fmt.Sprintf("DELETE FROM %s WHERE token_hash = ? AND user_id = ?", dbc.table("tokens"))
+	result, err := dbc.db.ExecContext(ctx, exec, tokenHash, dbc.userId)
+	if err != nil {
+		return 0, fmt.Errorf("deleteToken: %w", err)
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("deleteToken: %w", err)
+	}
+	return num, nil
+}
+
+func (dbc *DatabaseConn) setGrant(ctx context.Context, granteeId int64, permission Permission) error {
+	exec := fmt.Sprintf(`INSERT INTO %s (owner_id, grantee_id, permission) VALUES (?, ?, ?)
+		ON DUPLICATE KEY UPDATE permission = VALUES(permission)`, dbc.table("grants"))
+	if _, err := dbc.db.ExecContext(ctx, exec, dbc.userId, granteeId, permission); err != nil {
+		return fmt.Errorf("setGrant: %w", err)
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) deleteGrant(ctx context.Context, granteeId int64) error {
+	exec := fmt.Sprintf("DELETE FROM %s WHERE owner_id = ? AND grantee_id = ?", dbc.table("grants"))
+	if _, err := dbc.db.ExecContext(ctx, exec, dbc.userId, granteeId); err != nil {
+		return fmt.Errorf("deleteGrant: %w", err)
+	}
+	return nil
+}
+
+// grant returns the permission the owner gave the connection's user, or an
+// empty one if there is none.
+func (dbc *DatabaseConn) grant(ctx context.Context, ownerId int64) (Permission, error) {
+	var permission Permission
+	query := fmt.Sprintf("SELECT permission FROM %s WHERE owner_id = ? AND grantee_id = ?", dbc.table("grants"))
+	err := dbc.db.QueryRowContext(ctx, query, ownerId, dbc.userId).Scan(&permission)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("grant: %w", err)
+	}
+	return permission, nil
+}
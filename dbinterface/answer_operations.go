@@ -0,0 +1,26 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+)
+
+// RecordAnswer stores an answer typed in the quiz for the card with id
+// termId, and whether it was graded correct.
+func RecordAnswer(ctx context.Context, dbc *DatabaseConn, termId int64, answer string, correct bool) error {
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		if err := tx.ownTerm(ctx, termId); err != nil {
+			return err
+		}
+		if err := tx.addAnswer(ctx, termId, answer, correct); err != nil {
+			return err
+		}
+		return tx.recordReview(ctx, termId, correct, fmt.Sprintf("typed %q, %s", answer, verdict(correct)))
+	})
+}
+
+// Answers returns the answers typed for the card with id termId, oldest
+// first.
+func Answers(ctx context.Context, dbc *DatabaseConn, termId int64) ([]Review, error) {
+	return dbc.answers(ctx, termId)
+}
@@ -0,0 +1,37 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+)
+
+func (dbc *DatabaseConn) addAnswer(ctx context.Context, termId int64, answer string, correct bool) error {
+	exec := fmt.Sprintf("INSERT INTO %s (term_id, answer, correct) VALUES (?, ?, ?)", dbc.table("answers"))
+	if _, err := dbc.db.ExecContext(ctx, exec, termId, answer, correct); err != nil {
+		return fmt.Errorf("addAnswer: %w", err)
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) answers(ctx context.Context, termId int64) ([]Review, error) {
+	query := fmt.Sprintf(`SELECT a.answer, a.correct, a.answered_at FROM %s a JOIN %s t ON t.id = a.term_id
+		WHERE a.term_id = ? AND t.user_id = ? ORDER BY a.id`, dbc.table("answers"), dbc.tableName)
+	rows, err := dbc.db.QueryContext(ctx, query, termId, dbc.userId)
+	if err != nil {
+		return nil, fmt.Errorf("answers: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []Review
+	for rows.Next() {
+		var review Review
+		if err := rows.Scan(&review.Answer, &review.Correct, &review.ReviewedAt); err != nil {
+			return nil, fmt.Errorf("answers: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("answers: %w", err)
+	}
+	return reviews, nil
+}
@@ -0,0 +1,94 @@
+package dbinterface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/flashcards/backup"
+	"github.com/flashcards/database"
+)
+
+// Backup writes the rows of every table of the store, with the cards of all
+// users, to w as a backup archive, in one transaction so that it is
+// consistent. The archive records the schema version instead of the tables'
+// definitions.
+func Backup(ctx context.Context, dbc *DatabaseConn, w io.Writer) error {
+	archive := &backup.Archive{TableName: dbc.tableName, Created: time.Now()}
+	err := dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		names, err := tx.existingTables(ctx)
+		if err != nil {
+			return err
+		}
+		if archive.SchemaVersion, err = tx.schemaVersion(ctx); err != nil {
+			return err
+		}
+		archive.Tables = nil
+		for _, name := range names {
+			table, err := tx.dumpTable(ctx, name)
+			if err != nil {
+				return err
+			}
+			archive.Tables = append(archive.Tables, table)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return backup.Write(w, archive)
+}
+
+// RestoreBackup replaces every table of the store with the content of a
+// backup archive read from r. The tables are created from the current schema
+// and the migrations after the backup's schema version are applied to its
+// rows. Everything is loaded into tables of a store named with a _restore
+// suffix, which only replace the store's tables once they are complete, so
+// a failed restore leaves the store as it was.
+func RestoreBackup(ctx context.Context, dbc *DatabaseConn, r io.Reader) error {
+	if dbc.conn == nil {
+		return errors.New("RestoreBackup: cannot restore inside a transaction")
+	}
+	archive, err := backup.Read(r)
+	if err != nil {
+		return err
+	}
+	if archive.SchemaVersion > database.LatestVersion() {
+		return fmt.Errorf("RestoreBackup: backup has schema version %d, newer than %d", archive.SchemaVersion, database.LatestVersion())
+	}
+
+	staging := *dbc
+	staging.tableName = dbc.tableName + "_restore"
+	// Tables left by a restore that was interrupted are dropped first.
+	if err := staging.dropTables(ctx); err != nil {
+		return err
+	}
+	if err := staging.load(ctx, archive); err != nil {
+		if dropErr := staging.dropTables(ctx); dropErr != nil {
+			log.Printf("RestoreBackup: %v", dropErr)
+		}
+		return err
+	}
+	return dbc.swapTables(ctx, staging.tableName)
+}
+
+// load creates the tables of the store and fills them from archive.
+func (dbc *DatabaseConn) load(ctx context.Context, archive *backup.Archive) error {
+	if err := database.CreateAt(ctx, dbc.conn, dbc.tableName, archive.SchemaVersion); err != nil {
+		return err
+	}
+	for _, table := range archive.Tables {
+		// Older archives hold the migrations table, which the schema
+		// version replaces.
+		if table.Name == "migrations" {
+			continue
+		}
+		if err := dbc.loadTable(ctx, table); err != nil {
+			return err
+		}
+	}
+	return database.Migrate(ctx, dbc.conn, dbc.tableName)
+}
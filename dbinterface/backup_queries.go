@@ -0,0 +1,203 @@
+package dbinterface
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/flashcards/backup"
+	"github.com/flashcards/database"
+)
+
+// existingTables returns the tables of the store that are in the database,
+// which for an old schema is not all of them. The migrations table is left
+// out, since the schema version stands for it.
+func (dbc *DatabaseConn) existingTables(ctx context.Context) ([]string, error) {
+	var names []string
+	for _, name := range database.Tables(dbc.tableName) {
+		exists, err := dbc.tableExists(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("existingTables: %w", err)
+		}
+		if exists && name != dbc.table("migrations") {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+func (dbc *DatabaseConn) tableExists(ctx context.Context, name string) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`
+	if err := dbc.db.QueryRowContext(ctx, query, name).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (dbc *DatabaseConn) schemaVersion(ctx context.Context) (int, error) {
+	var version int
+	query := fmt.Sprintf("SELECT COALESCE(MAX(version), 0) FROM %s", dbc.table("migrations"))
+	if err := dbc.db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return 0, fmt.Errorf("schemaVersion: %w", err)
+	}
+	return version, nil
+}
+
+// dumpTable reads every row of a table of the store.
+func (dbc *DatabaseConn) dumpTable(ctx context.Context, name string) (backup.Table, error) {
+	table := backup.Table{Name: strings.TrimPrefix(strings.TrimPrefix(name, dbc.tableName), "_")}
+	rows, err := dbc.db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM `%s`", name))
+	if err != nil {
+		return table, fmt.Errorf("dumpTable %s: %w", name, err)
+	}
+	defer rows.Close()
+	if table.Columns, err = rows.Columns(); err != nil {
+		return table, fmt.Errorf("dumpTable %s: %w", name, err)
+	}
+	for rows.Next() {
+		row := make([]any, len(table.Columns))
+		pointers := make([]any, len(row))
+		for i := range row {
+			pointers[i] = &row[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return table, fmt.Errorf("dumpTable %s: %w", name, err)
+		}
+		for i, value := range row {
+			switch v := value.(type) {
+			case []byte:
+				row[i] = string(v)
+			case time.Time:
+				row[i] = v.Format(time.DateTime)
+			}
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return table, fmt.Errorf("dumpTable %s: %w", name, err)
+	}
+	return table, nil
+}
+
+// dropTables drops every table of the store.
+func (dbc *DatabaseConn) dropTables(ctx context.Context) error {
+	return dbc.dropAll(ctx, database.Tables(dbc.tableName))
+}
+
+func (dbc *DatabaseConn) dropAll(ctx context.Context, names []string) error {
+	for _, name := range names {
+		if _, err := dbc.db.ExecContext(ctx, fmt.Sprintf("DROP TABLE IF EXISTS `%s`", name)); err != nil {
+			return fmt.Errorf("dropTables: %w", err)
+		}
+	}
+	return nil
+}
+
+// swapTables replaces the tables of the store with those of the store called
+// from in a single RENAME TABLE, which MySQL applies atomically. The
+// replaced tables are renamed with an _old suffix and dropped afterwards.
+func (dbc *DatabaseConn) swapTables(ctx context.Context, from string) error {
+	live := database.Tables(dbc.tableName)
+	staged := database.Tables(from)
+	var renames, old []string
+	for i, name := range live {
+		exists, err := dbc.tableExists(ctx, name)
+		if err != nil {
+			return fmt.Errorf("swapTables: %w", err)
+		}
+		if exists {
+			renames = append(renames, fmt.Sprintf("`%s` TO `%s_old`", name, name))
+			old = append(old, name+"_old")
+		}
+		renames = append(renames, fmt.Sprintf("`%s` TO `%s`", staged[i], name))
+	}
+	if err := dbc.dropAll(ctx, old); err != nil {
+		return err
+	}
+	if _, err := dbc.db.ExecContext(ctx, "RENAME TABLE "+strings.Join(renames, ", ")); err != nil {
+		return fmt.Errorf("swapTables: %w", err)
+	}
+	return dbc.dropAll(ctx, old)
+}
+
+// columns returns the columns of a table of the store.
+func (dbc *DatabaseConn) columns(ctx context.Context, name string) (map[string]bool, error) {
+	query := `SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?`
+	rows, err := dbc.db.QueryContext(ctx, query, name)
+	if err != nil {
+		return nil, fmt.Errorf("columns %s: %w", name, err)
+	}
+	defer rows.Close()
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, fmt.Errorf("columns %s: %w", name, err)
+		}
+		columns[column] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("columns %s: %w", name, err)
+	}
+	return columns, nil
+}
+
+// loadTable inserts the rows of a table from a backup into the table of the
+// store created for it by database.CreateAt. Columns the table does not have
+// are dropped. Columns the backup does not have get their defaults, except
+// user_id: rows from before there were users belong to the default user, as
+// the users migration would have made them.
+func (dbc *DatabaseConn) loadTable(ctx context.Context, table backup.Table) error {
+	name := dbc.tableName
+	if table.Name != "" {
+		name = dbc.table(table.Name)
+	}
+	have, err := dbc.columns(ctx, name)
+	if err != nil {
+		return err
+	}
+	if len(have) == 0 {
+		return fmt.Errorf("loadTable: backup has unknown table %q", table.Name)
+	}
+	if len(table.Rows) == 0 {
+		return nil
+	}
+
+	var columns []string
+	var indexes []int
+	for i, column := range table.Columns {
+		if have[column] {
+			columns = append(columns, "`"+column+"`")
+			indexes = append(indexes, i)
+		}
+	}
+	addUser := have["user_id"] && !slices.Contains(table.Columns, "user_id")
+	if addUser {
+		columns = append(columns, "`user_id`")
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	exec := fmt.Sprintf("INSERT INTO `%s` (%s) VALUES (%s)", name, strings.Join(columns, ", "), placeholders)
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		for _, row := range table.Rows {
+			args := make([]any, 0, len(columns))
+			for _, i := range indexes {
+				value := row[i]
+				if n, ok := value.(json.Number); ok {
+					value = n.String()
+				}
+				args = append(args, value)
+			}
+			if addUser {
+				args = append(args, database.DefaultUserId)
+			}
+			if _, err := tx.db.ExecContext(ctx, exec, args...); err != nil {
+				return fmt.Errorf("loadTable %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,98 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flashcards/dict"
+)
+
+// BatchKind is the operation of one batch item.
+type BatchKind string
+
+const (
+	BatchAdd    BatchKind = "add"
+	BatchDelete BatchKind = "delete"
+	BatchEdit   BatchKind = "edit"
+)
+
+// BatchOp is one item of a batch. Definition is only used by BatchEdit.
+type BatchOp struct {
+	Kind       BatchKind
+	Term       string
+	Definition string
+}
+
+// BatchStatus is what happened to a batch item.
+type BatchStatus string
+
+const (
+	BatchApplied BatchStatus = "applied"
+	// BatchFailed marks the item that made the batch fail.
+	BatchFailed BatchStatus = "failed"
+	// BatchRolledBack marks items that succeeded but were undone because a
+	// later item failed.
+	BatchRolledBack BatchStatus = "rolled back"
+	// BatchSkipped marks items after the failed one, which were not tried.
+	BatchSkipped BatchStatus = "skipped"
+)
+
+// BatchResult reports on one batch item. Ids are the ids added by a
+// BatchAdd that was applied.
+type BatchResult struct {
+	Op     BatchOp
+	Status BatchStatus
+	Ids    []int64
+	Err    error
+}
+
+// Batch applies ops in order in one transaction. Either every op is applied
+// or, if one fails, none are and the error of the failed op is returned. The
+// results report on every op either way.
+func Batch(ctx context.Context, dbc *DatabaseConn, ops []BatchOp, dictionary dict.Dictionary) ([]BatchResult, error) {
+	results := make([]BatchResult, len(ops))
+	failed := -1
+	err := dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		for i, op := range ops {
+			results[i] = BatchResult{Op: op, Status: BatchApplied}
+			var err error
+			switch op.Kind {
+			case BatchAdd:
+				results[i].Ids, err = Add(ctx, tx, op.Term, dictionary)
+			case BatchDelete:
+				err = Delete(ctx, tx, op.Term)
+			case BatchEdit:
+				err = Edit(ctx, tx, op.Term, op.Definition)
+			default:
+				err = fmt.Errorf("Batch: unknown operation %q", op.Kind)
+			}
+			if err != nil {
+				failed = i
+				results[i].Err = err
+				return err
+			}
+		}
+		failed = -1
+		return nil
+	})
+	if err == nil {
+		return results, nil
+	}
+
+	// Every op was tried if the commit itself failed.
+	if failed < 0 {
+		failed = len(ops)
+	}
+	for i := range results {
+		switch {
+		case i < failed:
+			results[i].Status = BatchRolledBack
+			results[i].Ids = nil
+		case i == failed:
+			results[i].Status = BatchFailed
+		default:
+			results[i] = BatchResult{Op: ops[i], Status: BatchSkipped}
+		}
+	}
+	return results, err
+}
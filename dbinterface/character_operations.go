@@ -0,0 +1,41 @@
+package dbinterface
+
+import (
+	"context"
+	"github.com/flashcards/dict"
+)
+
+// Structure returns the radical, stroke count and components stored for a
+// character card, or nil if the card has none.
+func Structure(ctx context.Context, dbc *DatabaseConn, term string) (*dict.Decomposition, error) {
+	ids, err := dbc.findTerm(ctx, term)
+	if err != nil {
+		return nil, err
+	}
+	return dbc.characterOf(ctx, ids[0])
+}
+
+// SharingComponents returns, for each component of a character card, the
+// other cards containing that component.
+func SharingComponents(ctx context.Context, dbc *DatabaseConn, term string) (map[string]map[int64]TermDef, error) {
+	d, err := Structure(ctx, dbc, term)
+	if err != nil || d == nil {
+		return nil, err
+	}
+	sharing := make(map[string]map[int64]TermDef)
+	for _, component := range d.Components {
+		terms, err := dbc.listAll(ctx, Filter{Component: component})
+		if err != nil {
+			return nil, err
+		}
+		for id, termDef := range terms {
+			if termDef.Term == term {
+				delete(terms, id)
+			}
+		}
+		if len(terms) > 0 {
+			sharing[component] = terms
+		}
+	}
+	return sharing, nil
+}
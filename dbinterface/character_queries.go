@@ -0,0 +1,62 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flashcards/dict"
+)
+
+func (dbc *DatabaseConn) addCharacter(ctx context.Context, termId int64, d *dict.Decomposition) error {
+	exec := fmt.Sprintf("INSERT INTO %s (term_id, radical, strokes, decomposition) VALUES (?, ?, ?, ?)", dbc.table("characters"))
+	if _, err := dbc.db.ExecContext(ctx, exec, termId, d.Radical, d.Strokes, d.IDS); err != nil {
+		return fmt.Errorf("addCharacter: %w", err)
+	}
+	exec = fmt.Sprintf("INSERT IGNORE INTO %s (term_id, component) VALUES (?, ?)", dbc.table("components"))
+	for _, component := range d.Components {
+		if _, err := dbc.db.ExecContext(ctx, exec, termId, component); err != nil {
+			return fmt.Errorf("addCharacter: %w", err)
+		}
+	}
+	return nil
+}
+
+// characterOf returns the stored structure of a character card, or nil if
+// there is none.
+func (dbc *DatabaseConn) characterOf(ctx context.Context, termId int64) (*dict.Decomposition, error) {
+	query := fmt.Sprintf("SELECT radical, strokes, decomposition FROM %s WHERE term_id = ?", dbc.table("characters"))
+	rows, err := dbc.db.QueryContext(ctx, query, termId)
+	if err != nil {
+		return nil, fmt.Errorf("characterOf: %w", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("characterOf: %w", err)
+		}
+		return nil, nil
+	}
+	var d dict.Decomposition
+	if err := rows.Scan(&d.Radical, &d.Strokes, &d.IDS); err != nil {
+		return nil, fmt.Errorf("characterOf: %w", err)
+	}
+	rows.Close()
+
+	query = fmt.Sprintf("SELECT component FROM %s WHERE term_id = ?", dbc.table("components"))
+	componentRows, err := dbc.db.QueryContext(ctx, query, termId)
+	if err != nil {
+		return nil, fmt.Errorf("characterOf: %w", err)
+	}
+	defer componentRows.Close()
+	for componentRows.Next() {
+		var component string
+		if err := componentRows.Scan(&component); err != nil {
+			return nil, fmt.Errorf("characterOf: %w", err)
+		}
+		d.Components = append(d.Components, component)
+	}
+	if err := componentRows.Err(); err != nil {
+		return nil, fmt.Errorf("characterOf: %w", err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,72 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/flashcards/sentences"
+)
+
+// AddCloze creates a cloze card for an existing term from a sentence
+// containing it, such as a pinned example.
+func AddCloze(ctx context.Context, dbc *DatabaseConn, term string, sentence sentences.Sentence) (int64, error) {
+	if err := verifyLanguage(dbc.language, term); err != nil {
+		return 0, err
+	}
+	if !strings.Contains(sentence.Text, term) {
+		return 0, fmt.Errorf("AddCloze: %q does not contain %q", sentence.Text, term)
+	}
+	var clozeId int64
+	err := dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		ids, err := tx.findTerm(ctx, term)
+		if err != nil {
+			return err
+		}
+		if clozeId, err = tx.addCloze(ctx, ids[0], sentence.Text, sentence.Translation); err != nil {
+			return err
+		}
+		return tx.addHistory(ctx, ids[:1], HistoryCloze, sentence.Text)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return clozeId, nil
+}
+
+// PinnedSentences returns the sentences pinned to a card.
+func PinnedSentences(ctx context.Context, dbc *DatabaseConn, term string) ([]sentences.Sentence, error) {
+	ids, err := dbc.findTerm(ctx, term)
+	if err != nil {
+		return nil, err
+	}
+	return dbc.pinnedSentences(ctx, ids[0])
+}
+
+// ListClozes returns the cloze cards of terms that are not leeches, keyed by
+// their id.
+func ListClozes(ctx context.Context, dbc *DatabaseConn) (map[int64]Cloze, error) {
+	return dbc.listClozes(ctx)
+}
+
+// RecordClozeReview stores an answer to a cloze card. Cloze reviews are kept
+// apart from the reviews of the term's own card: they count in the
+// statistics and the term's history, but a failed cloze is not a lapse of
+// the card and cannot make it a leech.
+func RecordClozeReview(ctx context.Context, dbc *DatabaseConn, clozeId int64, answer string, correct bool) error {
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		termId, err := tx.clozeTerm(ctx, clozeId)
+		if err != nil {
+			return err
+		}
+		if err := tx.addClozeReview(ctx, clozeId, answer, correct); err != nil {
+			return err
+		}
+		return tx.logReview(ctx, termId, correct, fmt.Sprintf("cloze %d answered %q, %s", clozeId, answer, verdict(correct)))
+	})
+}
+
+// ClozeReviews returns the answers given to a cloze card, oldest first.
+func ClozeReviews(ctx context.Context, dbc *DatabaseConn, clozeId int64) ([]Review, error) {
+	return dbc.clozeReviews(ctx, clozeId)
+}
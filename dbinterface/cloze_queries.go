@@ -0,0 +1,108 @@
+package dbinterface
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Cloze is a sentence card testing Term by blanking it out of Sentence.
+type Cloze struct {
+	TermId      int64
+	Term        string
+	Sentence    string
+	Translation string
+}
+
+// Review is one graded answer to a card.
+type Review struct {
+	Answer     string
+	Correct    bool
+	ReviewedAt time.Time
+}
+
+func (dbc *DatabaseConn) addCloze(ctx context.Context, termId int64, sentence, translation string) (int64, error) {
+	exec := fmt.Sprintf("INSERT INTO %s (term_id, sentence, translation) VALUES (?, ?, ?)", dbc.table("clozes"))
+	result, err := dbc.db.ExecContext(ctx, exec, termId, sentence, translation)
+	if err != nil {
+		return 0, fmt.Errorf("addCloze: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("addCloze: %w", err)
+	}
+	return id, nil
+}
+
+func (dbc *DatabaseConn) listClozes(ctx context.Context) (map[int64]Cloze, error) {
+	query := fmt.Sprintf(`SELECT c.id, c.term_id, t.term, c.sentence, c.translation FROM %s c JOIN %s t ON t.id = c.term_id
+		WHERE t.user_id = ? AND t.language = ? AND t.deleted_at IS NULL AND %s`,
+		dbc.table("clozes"), dbc.tableName, dbc.notLeech())
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, dbc.language.Name())
+	if err != nil {
+		return nil, fmt.Errorf("listClozes: %w", err)
+	}
+	defer rows.Close()
+
+	clozes := make(map[int64]Cloze)
+	for rows.Next() {
+		var id int64
+		var cloze Cloze
+		if err := rows.Scan(&id, &cloze.TermId, &cloze.Term, &cloze.Sentence, &cloze.Translation); err != nil {
+			return nil, fmt.Errorf("listClozes: %w", err)
+		}
+		clozes[id] = cloze
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listClozes: %w", err)
+	}
+	return clozes, nil
+}
+
+func (dbc *DatabaseConn) clozeTerm(ctx context.Context, clozeId int64) (int64, error) {
+	var termId int64
+	query := fmt.Sprintf("SELECT c.term_id FROM %s c JOIN %s t ON t.id = c.term_id WHERE c.id = ? AND t.user_id = ?",
+		dbc.table("clozes"), dbc.tableName)
+	err := dbc.db.QueryRowContext(ctx, query, clozeId, dbc.userId).Scan(&termId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, &ErrNotFound{term: fmt.Sprintf("cloze %d", clozeId)}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("clozeTerm: %w", err)
+	}
+	return termId, nil
+}
+
+func (dbc *DatabaseConn) addClozeReview(ctx context.Context, clozeId int64, answer string, correct bool) error {
+	exec := fmt.Sprintf("INSERT INTO %s (cloze_id, answer, correct) VALUES (?, ?, ?)", dbc.table("cloze_reviews"))
+	if _, err := dbc.db.ExecContext(ctx, exec, clozeId, answer, correct); err != nil {
+		return fmt.Errorf("addClozeReview: %w", err)
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) clozeReviews(ctx context.Context, clozeId int64) ([]Review, error) {
+	query := fmt.Sprintf(`SELECT r.answer, r.correct, r.reviewed_at FROM %s r
+		JOIN %s c ON c.id = r.cloze_id JOIN %s t ON t.id = c.term_id
+		WHERE r.cloze_id = ? AND t.user_id = ? ORDER BY r.id`, dbc.table("cloze_reviews"), dbc.table("clozes"), dbc.tableName)
+	rows, err := dbc.db.QueryContext(ctx, query, clozeId, dbc.userId)
+	if err != nil {
+		return nil, fmt.Errorf("clozeReviews: %w", err)
+	}
+	defer rows.Close()
+
+	var reviews []Review
+	for rows.Next() {
+		var review Review
+		if err := rows.Scan(&review.Answer, &review.Correct, &review.ReviewedAt); err != nil {
+			return nil, fmt.Errorf("clozeReviews: %w", err)
+		}
+		reviews = append(reviews, review)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("clozeReviews: %w", err)
+	}
+	return reviews, nil
+}
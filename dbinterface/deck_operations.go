@@ -0,0 +1,217 @@
+package dbinterface
+
+import (
+	"context"
+	"errors"
+
+	"github.com/flashcards/dict"
+	"github.com/flashcards/language"
+)
+
+// CreateDeck creates an empty deck for the connection's language.
+func CreateDeck(ctx context.Context, dbc *DatabaseConn, name string) (int64, error) {
+	return dbc.createDeck(ctx, name, dbc.language.Name())
+}
+
+func RenameDeck(ctx context.Context, dbc *DatabaseConn, oldName, newName string) error {
+	id, _, err := dbc.findDeck(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	return dbc.renameDeck(ctx, id, newName)
+}
+
+// DeleteDeck deletes a deck. The terms in it are kept.
+func DeleteDeck(ctx context.Context, dbc *DatabaseConn, name string) error {
+	id, _, err := dbc.findDeck(ctx, name)
+	if err != nil {
+		return err
+	}
+	return dbc.deleteDeck(ctx, id)
+}
+
+func ListDecks(ctx context.Context, dbc *DatabaseConn) (map[int64]Deck, error) {
+	return dbc.listDecks(ctx)
+}
+
+// deckTerm looks up a deck and the ids of a term about to be put in it,
+// checking the term against the deck's language and looking it up among the
+// cards of that language.
+func deckTerm(ctx context.Context, dbc *DatabaseConn, deckName, term string) (int64, []int64, error) {
+	deckId, deck, err := dbc.findDeck(ctx, deckName)
+	if err != nil {
+		return 0, nil, err
+	}
+	lang, err := language.ByName(deck.Language)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := verifyLanguage(lang, term); err != nil {
+		return 0, nil, err
+	}
+	termIds, err := dbc.inLanguage(lang).findTerm(ctx, term)
+	if err != nil {
+		return 0, nil, err
+	}
+	return deckId, termIds, nil
+}
+
+// AddToDeck puts an existing term in a deck.
+func AddToDeck(ctx context.Context, dbc *DatabaseConn, deckName, term string) error {
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		deckId, termIds, err := deckTerm(ctx, tx, deckName, term)
+		if err != nil {
+			return err
+		}
+		if err := tx.addToDeck(ctx, deckId, termIds); err != nil {
+			return err
+		}
+		return tx.addHistory(ctx, termIds, HistoryAddedToDeck, deckName)
+	})
+}
+
+// RemoveFromDeck takes a term out of a deck without deleting it.
+func RemoveFromDeck(ctx context.Context, dbc *DatabaseConn, deckName, term string) error {
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		deckId, termIds, err := deckTerm(ctx, tx, deckName, term)
+		if err != nil {
+			return err
+		}
+		if err := tx.removeFromDeck(ctx, deckId, termIds); err != nil {
+			return err
+		}
+		return tx.addHistory(ctx, termIds, HistoryRemovedFromDeck, deckName)
+	})
+}
+
+// Tag tags an existing term, creating the tag if needed.
+func Tag(ctx context.Context, dbc *DatabaseConn, term, tag string) error {
+	if err := verifyLanguage(dbc.language, term); err != nil {
+		return err
+	}
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		termIds, err := tx.findTerm(ctx, term)
+		if err != nil {
+			return err
+		}
+		tagId, err := tx.findOrCreateTag(ctx, tag)
+		if err != nil {
+			return err
+		}
+		if err := tx.tagTerms(ctx, termIds, tagId); err != nil {
+			return err
+		}
+		return tx.addHistory(ctx, termIds, HistoryTagged, tag)
+	})
+}
+
+func Untag(ctx context.Context, dbc *DatabaseConn, term, tag string) error {
+	if err := verifyLanguage(dbc.language, term); err != nil {
+		return err
+	}
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		termIds, err := tx.findTerm(ctx, term)
+		if err != nil {
+			return err
+		}
+		if err := tx.untagTerms(ctx, termIds, tag); err != nil {
+			return err
+		}
+		return tx.addHistory(ctx, termIds, HistoryUntagged, tag)
+	})
+}
+
+// Tags returns the tags of a term.
+func Tags(ctx context.Context, dbc *DatabaseConn, term string) ([]string, error) {
+	termIds, err := dbc.findTerm(ctx, term)
+	if err != nil {
+		return nil, err
+	}
+	var tags []string
+	for _, id := range termIds {
+		idTags, err := dbc.tagsOf(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, idTags...)
+	}
+	return tags, nil
+}
+
+// AddNewWordsToDeck adds the words that are not cards yet and puts them in
+// deckName, creating the deck if needed. Words that already are cards are
+// skipped. It returns the ids of all added cards, including those Add
+// creates for the characters of each word.
+func AddNewWordsToDeck(ctx context.Context, dbc *DatabaseConn, deckName string, words []string, dictionary dict.Dictionary) ([]int64, error) {
+	known, err := KnownTerms(ctx, dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, err := dbc.findDeck(ctx, deckName); err != nil {
+		var notFound *ErrNotFound
+		if !errors.As(err, &notFound) {
+			return nil, err
+		}
+		if _, err := CreateDeck(ctx, dbc, deckName); err != nil {
+			return nil, err
+		}
+	}
+
+	var addedIds []int64
+	for _, word := range words {
+		if known[word] {
+			continue
+		}
+		ids, err := Add(ctx, dbc, word, dictionary)
+		if err != nil {
+			return addedIds, err
+		}
+		addedIds = append(addedIds, ids...)
+		if err := AddToDeck(ctx, dbc, deckName, word); err != nil {
+			return addedIds, err
+		}
+		for _, c := range dbc.language.Tokenize(word) {
+			known[c] = true
+		}
+		known[word] = true
+	}
+	return addedIds, nil
+}
+
+// KnownTerms returns the set of terms that are cards. Callers showing
+// examples for many cards get it once and pass it to each Examples call.
+func KnownTerms(ctx context.Context, dbc *DatabaseConn) (map[string]bool, error) {
+	existing, err := dbc.listAll(ctx, Filter{})
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(existing))
+	for _, termDef := range existing {
+		known[termDef.Term] = true
+	}
+	return known, nil
+}
+
+// NextUnknownWords returns the first n words that are not cards yet, skipping
+// words outside the connection's language or missing from dictionary.
+func NextUnknownWords(ctx context.Context, dbc *DatabaseConn, words []string, n int, dictionary dict.Dictionary) ([]string, error) {
+	known, err := KnownTerms(ctx, dbc)
+	if err != nil {
+		return nil, err
+	}
+	var unknown []string
+	for _, word := range words {
+		if len(unknown) == n {
+			break
+		}
+		if known[word] || verifyLanguage(dbc.language, word) != nil {
+			continue
+		}
+		if _, inDict := dictionary.Lookup(word); !inDict {
+			continue
+		}
+		unknown = append(unknown, word)
+	}
+	return unknown, nil
+}
@@ -0,0 +1,197 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/flashcards/database"
+)
+
+type Deck struct {
+	Name     string
+	Language string
+}
+
+func (dbc *DatabaseConn) createDeck(ctx context.Context, name, language string) (int64, error) {
+	exec := fmt.Sprintf("INSERT INTO %s (user_id, name, language) VALUES (?, ?, ?)", dbc.table("decks"))
+	result, err := dbc.db.ExecContext(ctx, exec, dbc.userId, name, language)
+	if err != nil {
+		return 0, fmt.Errorf("createDeck %q: %w", name, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("createDeck %q: %w", name, err)
+	}
+	return id, nil
+}
+
+func (dbc *DatabaseConn) findDeck(ctx context.Context, name string) (int64, Deck, error) {
+	query := fmt.Sprintf("SELECT id, name, language FROM %s WHERE user_id = ? AND name = ?", dbc.table("decks"))
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, name)
+	if err != nil {
+		return 0, Deck{}, fmt.Errorf("findDeck %q: %w", name, err)
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, Deck{}, fmt.Errorf("findDeck %q: %w", name, err)
+		}
+		return 0, Deck{}, &ErrNotFound{term: name}
+	}
+	var id int64
+	var deck Deck
+	if err := rows.Scan(&id, &deck.Name, &deck.Language); err != nil {
+		return 0, Deck{}, fmt.Errorf("findDeck %q: %w", name, err)
+	}
+	return id, deck, nil
+}
+
+func (dbc *DatabaseConn) renameDeck(ctx context.Context, id int64, newName string) error {
+	exec := fmt.Sprintf("UPDATE %s SET name = ? WHERE id = ? AND user_id = ?", dbc.table("decks"))
+	if _, err := dbc.db.ExecContext(ctx, exec, newName, id, dbc.userId); err != nil {
+		return fmt.Errorf("renameDeck %q: %w", newName, err)
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) deleteDeck(ctx context.Context, id int64) error {
+	for _, table := range []string{dbc.table("deck_terms"), dbc.table("deck_directions")} {
+		exec := fmt.Sprintf("DELETE FROM %s WHERE deck_id = ?", table)
+		if _, err := dbc.db.ExecContext(ctx, exec, id); err != nil {
+			return fmt.Errorf("deleteDeck: %w", err)
+		}
+	}
+	exec := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND user_id = ?", dbc.table("decks"))
+	if _, err := dbc.db.ExecContext(ctx, exec, id, dbc.userId); err != nil {
+		return fmt.Errorf("deleteDeck: %w", err)
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) listDecks(ctx context.Context) (map[int64]Deck, error) {
+	rows, err := dbc.db.QueryContext(ctx, fmt.Sprintf("SELECT id, name, language FROM %s WHERE user_id = ?", dbc.table("decks")), dbc.userId)
+	if err != nil {
+		return nil, fmt.Errorf("listDecks: %w", err)
+	}
+	defer rows.Close()
+
+	decks := make(map[int64]Deck)
+	for rows.Next() {
+		var id int64
+		var deck Deck
+		if err := rows.Scan(&id, &deck.Name, &deck.Language); err != nil {
+			return nil, fmt.Errorf("listDecks: %w", err)
+		}
+		decks[id] = deck
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listDecks: %w", err)
+	}
+	return decks, nil
+}
+
+func (dbc *DatabaseConn) addToDeck(ctx context.Context, deckId int64, termIds []int64) error {
+	exec := fmt.Sprintf("INSERT IGNORE INTO %s (deck_id, term_id) VALUES (?, ?)", dbc.table("deck_terms"))
+	for _, termId := range termIds {
+		if _, err := dbc.db.ExecContext(ctx, exec, deckId, termId); err != nil {
+			return fmt.Errorf("addToDeck: %w", err)
+		}
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) removeFromDeck(ctx context.Context, deckId int64, termIds []int64) error {
+	exec := fmt.Sprintf("DELETE FROM %s WHERE deck_id = ? AND term_id = ?", dbc.table("deck_terms"))
+	for _, termId := range termIds {
+		if _, err := dbc.db.ExecContext(ctx, exec, deckId, termId); err != nil {
+			return fmt.Errorf("removeFromDeck: %w", err)
+		}
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) findOrCreateTag(ctx context.Context, name string) (int64, error) {
+	exec := fmt.Sprintf("INSERT IGNORE INTO %s (user_id, name) VALUES (?, ?)", dbc.table("tags"))
+	if _, err := dbc.db.ExecContext(ctx, exec, dbc.userId, name); err != nil {
+		return 0, fmt.Errorf("findOrCreateTag %q: %w", name, err)
+	}
+	var id int64
+	query := fmt.Sprintf("SELECT id FROM %s WHERE user_id = ? AND name = ?", dbc.table("tags"))
+	if err := dbc.db.QueryRowContext(ctx, query, dbc.userId, name).Scan(&id); err != nil {
+		return 0, fmt.Errorf("findOrCreateTag %q: %w", name, err)
+	}
+	return id, nil
+}
+
+func (dbc *DatabaseConn) tagTerms(ctx context.Context, termIds []int64, tagId int64) error {
+	exec := fmt.Sprintf("INSERT IGNORE INTO %s (term_id, tag_id) VALUES (?, ?)", dbc.table("term_tags"))
+	for _, termId := range termIds {
+		if _, err := dbc.db.ExecContext(ctx, exec, termId, tagId); err != nil {
+			return fmt.Errorf("tagTerms: %w", err)
+		}
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) untagTerms(ctx context.Context, termIds []int64, tag string) error {
+	exec := fmt.Sprintf(`DELETE tt FROM %s tt JOIN %s g ON g.id = tt.tag_id
+		WHERE tt.term_id = ? AND g.user_id = ? AND g.name = ?`, dbc.table("term_tags"), dbc.table("tags"))
+	for _, termId := range termIds {
+		if _, err := dbc.db.ExecContext(ctx, exec, termId, dbc.userId, tag); err != nil {
+			return fmt.Errorf("untagTerms: %w", err)
+		}
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) tagsOf(ctx context.Context, termId int64) ([]string, error) {
+	query := fmt.Sprintf(`SELECT g.name FROM %s tt JOIN %s g ON g.id = tt.tag_id
+		WHERE tt.term_id = ? AND g.user_id = ? ORDER BY g.name`, dbc.table("term_tags"), dbc.table("tags"))
+	rows, err := dbc.db.QueryContext(ctx, query, termId, dbc.userId)
+	if err != nil {
+		return nil, fmt.Errorf("tagsOf: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []string
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("tagsOf: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("tagsOf: %w", err)
+	}
+	return tags, nil
+}
+
+// deleteLinks removes everything linked to purged terms, from deck
+// memberships and tags to cloze cards and review history, so that no rows
+// are left pointing at ids that no longer exist.
+func (dbc *DatabaseConn) deleteLinks(ctx context.Context, termIds []int64) error {
+	if len(termIds) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(termIds)), ", ")
+	args := make([]any, len(termIds))
+	for i, id := range termIds {
+		args[i] = id
+	}
+	exec := fmt.Sprintf("DELETE r FROM %s r JOIN %s c ON c.id = r.cloze_id WHERE c.term_id IN (%s)",
+		dbc.table("cloze_reviews"), dbc.table("clozes"), placeholders)
+	if _, err := dbc.db.ExecContext(ctx, exec, args...); err != nil {
+		return err
+	}
+	for _, suffix := range database.TermLinks {
+		table := dbc.table(suffix)
+		exec := fmt.Sprintf("DELETE FROM %s WHERE term_id IN (%s)", table, placeholders)
+		if _, err := dbc.db.ExecContext(ctx, exec, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
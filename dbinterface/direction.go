@@ -0,0 +1,54 @@
+package dbinterface
+
+import "fmt"
+
+// Direction is which side of a term a card shows and which it asks for. Each
+// direction is reviewed and graded on its own.
+type Direction string
+
+const (
+	TermToDefinition Direction = "term-definition"
+	DefinitionToTerm Direction = "definition-term"
+	PinyinToHanzi    Direction = "pinyin-hanzi"
+	HanziToPinyin    Direction = "hanzi-pinyin"
+)
+
+var Directions = []Direction{TermToDefinition, DefinitionToTerm, PinyinToHanzi, HanziToPinyin}
+
+func ParseDirection(s string) (Direction, error) {
+	for _, direction := range Directions {
+		if string(direction) == s {
+			return direction, nil
+		}
+	}
+	return "", &ErrInvalidDirection{direction: s}
+}
+
+// Grade is the self-assessed result of reviewing a card.
+type Grade int
+
+const (
+	Wrong Grade = iota
+	Unsure
+	Right
+)
+
+func (g Grade) String() string {
+	switch g {
+	case Wrong:
+		return "wrong"
+	case Unsure:
+		return "unsure"
+	case Right:
+		return "right"
+	}
+	return fmt.Sprintf("Grade(%d)", int(g))
+}
+
+// Card is one direction of a term.
+type Card struct {
+	TermId     int64
+	Term       string
+	Definition string
+	Direction  Direction
+}
@@ -0,0 +1,69 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetDeckDirections enables the given card directions for a deck. An empty
+// list enables every direction, which is also the default for new decks.
+func SetDeckDirections(ctx context.Context, dbc *DatabaseConn, deckName string, directions []Direction) error {
+	id, _, err := dbc.findDeck(ctx, deckName)
+	if err != nil {
+		return err
+	}
+	return dbc.setDeckDirections(ctx, id, directions)
+}
+
+// DeckDirections returns the card directions enabled for a deck.
+func DeckDirections(ctx context.Context, dbc *DatabaseConn, deckName string) ([]Direction, error) {
+	id, _, err := dbc.findDeck(ctx, deckName)
+	if err != nil {
+		return nil, err
+	}
+	return dbc.deckDirections(ctx, id)
+}
+
+// Cards returns a card for every enabled direction of each term matching
+// filter, leaving out leeches. Without a deck in the filter every direction
+// is enabled.
+func Cards(ctx context.Context, dbc *DatabaseConn, filter Filter) ([]Card, error) {
+	directions := Directions
+	if filter.Deck != "" {
+		var err error
+		if directions, err = DeckDirections(ctx, dbc, filter.Deck); err != nil {
+			return nil, err
+		}
+	}
+	terms, err := dbc.listAll(ctx, WithoutLeeches(filter))
+	if err != nil {
+		return nil, err
+	}
+	var cards []Card
+	for id, termDef := range terms {
+		for _, direction := range directions {
+			cards = append(cards, Card{TermId: id, Term: termDef.Term, Definition: termDef.Definition, Direction: direction})
+		}
+	}
+	return cards, nil
+}
+
+// RecordGrade stores the grade of one review of a card. Only Right counts
+// as a correct review; anything else is a lapse.
+func RecordGrade(ctx context.Context, dbc *DatabaseConn, card Card, grade Grade) error {
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		if err := tx.ownTerm(ctx, card.TermId); err != nil {
+			return err
+		}
+		if err := tx.addDirectionReview(ctx, card.TermId, card.Direction, grade); err != nil {
+			return err
+		}
+		return tx.recordReview(ctx, card.TermId, grade == Right, fmt.Sprintf("%s graded %s", card.Direction, grade))
+	})
+}
+
+// LastGrades returns the latest grade of each term's card in one direction.
+// Terms whose card in that direction was never reviewed are left out.
+func LastGrades(ctx context.Context, dbc *DatabaseConn, direction Direction) (map[int64]Grade, error) {
+	return dbc.lastGrades(ctx, direction)
+}
@@ -0,0 +1,85 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+)
+
+func (dbc *DatabaseConn) setDeckDirections(ctx context.Context, deckId int64, directions []Direction) error {
+	exec := fmt.Sprintf("DELETE FROM %s WHERE deck_id = ?", dbc.table("deck_directions"))
+	if _, err := dbc.db.ExecContext(ctx, exec, deckId); err != nil {
+		return fmt.Errorf("setDeckDirections: %w", err)
+	}
+	exec = fmt.Sprintf("INSERT IGNORE INTO %s (deck_id, direction) VALUES (?, ?)", dbc.table("deck_directions"))
+	for _, direction := range directions {
+		if _, err := dbc.db.ExecContext(ctx, exec, deckId, direction); err != nil {
+			return fmt.Errorf("setDeckDirections: %w", err)
+		}
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) deckDirections(ctx context.Context, deckId int64) ([]Direction, error) {
+	query := fmt.Sprintf("SELECT direction FROM %s WHERE deck_id = ?", dbc.table("deck_directions"))
+	rows, err := dbc.db.QueryContext(ctx, query, deckId)
+	if err != nil {
+		return nil, fmt.Errorf("deckDirections: %w", err)
+	}
+	defer rows.Close()
+
+	enabled := make(map[Direction]bool)
+	for rows.Next() {
+		var direction Direction
+		if err := rows.Scan(&direction); err != nil {
+			return nil, fmt.Errorf("deckDirections: %w", err)
+		}
+		enabled[direction] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("deckDirections: %w", err)
+	}
+	if len(enabled) == 0 {
+		return Directions, nil
+	}
+	// Keep the order of Directions rather than the database's.
+	var directions []Direction
+	for _, direction := range Directions {
+		if enabled[direction] {
+			directions = append(directions, direction)
+		}
+	}
+	return directions, nil
+}
+
+func (dbc *DatabaseConn) addDirectionReview(ctx context.Context, termId int64, direction Direction, grade Grade) error {
+	exec := fmt.Sprintf("INSERT INTO %s (term_id, direction, grade) VALUES (?, ?, ?)", dbc.table("direction_reviews"))
+	if _, err := dbc.db.ExecContext(ctx, exec, termId, direction, grade); err != nil {
+		return fmt.Errorf("addDirectionReview: %w", err)
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) lastGrades(ctx context.Context, direction Direction) (map[int64]Grade, error) {
+	query := fmt.Sprintf(`SELECT r.term_id, r.grade FROM %[1]s r JOIN %[2]s t ON t.id = r.term_id
+		WHERE t.user_id = ? AND r.id IN (SELECT MAX(id) FROM %[1]s WHERE direction = ? GROUP BY term_id)`,
+		dbc.table("direction_reviews"), dbc.tableName)
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, direction)
+	if err != nil {
+		return nil, fmt.Errorf("lastGrades: %w", err)
+	}
+	defer rows.Close()
+
+	grades := make(map[int64]Grade)
+	for rows.Next() {
+		var termId int64
+		var grade Grade
+		if err := rows.Scan(&termId, &grade); err != nil {
+			return nil, fmt.Errorf("lastGrades: %w", err)
+		}
+		grades[termId] = grade
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("lastGrades: %w", err)
+	}
+	return grades, nil
+}
@@ -18,3 +18,59 @@ type ErrUnexpectedLanguage struct {
 func (e *ErrUnexpectedLanguage) Error() string {
 	return fmt.Sprintf("%q is not in expected language of %s", e.term, e.expectedLanguage)
 }
+
+type ErrInvalidFilter struct {
+	expr   string
+	reason string
+}
+
+func (e *ErrInvalidFilter) Error() string {
+	return fmt.Sprintf("Invalid filter %q: %s", e.expr, e.reason)
+}
+
+type ErrInvalidDirection struct {
+	direction string
+}
+
+func (e *ErrInvalidDirection) Error() string {
+	return fmt.Sprintf("Invalid card direction %q", e.direction)
+}
+
+type ErrUserExists struct {
+	name string
+}
+
+func (e *ErrUserExists) Error() string {
+	return fmt.Sprintf("User %q already exists", e.name)
+}
+
+type ErrInvalidCredentials struct{}
+
+func (e *ErrInvalidCredentials) Error() string {
+	return "Invalid user name, password or token"
+}
+
+type ErrForbidden struct {
+	owner      string
+	permission Permission
+}
+
+func (e *ErrForbidden) Error() string {
+	return fmt.Sprintf("No %s permission on the cards of %q", e.permission, e.owner)
+}
+
+type ErrInvalidPermission struct {
+	permission string
+}
+
+func (e *ErrInvalidPermission) Error() string {
+	return fmt.Sprintf("Invalid permission %q", e.permission)
+}
+
+type ErrInvalidPassword struct {
+	reason string
+}
+
+func (e *ErrInvalidPassword) Error() string {
+	return fmt.Sprintf("Invalid password: %s", e.reason)
+}
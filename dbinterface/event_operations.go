@@ -0,0 +1,18 @@
+package dbinterface
+
+import (
+	"context"
+	"time"
+
+	"github.com/flashcards/stats"
+)
+
+// Stats reports learning progress from the recorded events: cards being
+// added and every graded review, whether self-graded, typed or cloze.
+func Stats(ctx context.Context, dbc *DatabaseConn) (stats.Report, error) {
+	events, err := dbc.listEvents(ctx)
+	if err != nil {
+		return stats.Report{}, err
+	}
+	return stats.Compute(events, time.Now()), nil
+}
@@ -0,0 +1,39 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flashcards/stats"
+)
+
+func (dbc *DatabaseConn) addEvent(ctx context.Context, termId int64, kind stats.Kind, correct bool) error {
+	exec := fmt.Sprintf("INSERT INTO %s (term_id, kind, correct) VALUES (?, ?, ?)", dbc.table("review_events"))
+	if _, err := dbc.db.ExecContext(ctx, exec, termId, kind, correct); err != nil {
+		return fmt.Errorf("addEvent: %w", err)
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) listEvents(ctx context.Context) ([]stats.Event, error) {
+	query := fmt.Sprintf(`SELECT e.term_id, t.term, e.kind, e.correct, e.happened_at FROM %s e JOIN %s t ON t.id = e.term_id
+		WHERE t.user_id = ? ORDER BY e.id`, dbc.table("review_events"), dbc.tableName)
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId)
+	if err != nil {
+		return nil, fmt.Errorf("listEvents: %w", err)
+	}
+	defer rows.Close()
+
+	var events []stats.Event
+	for rows.Next() {
+		var e stats.Event
+		if err := rows.Scan(&e.TermId, &e.Term, &e.Kind, &e.Correct, &e.At); err != nil {
+			return nil, fmt.Errorf("listEvents: %w", err)
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listEvents: %w", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,169 @@
+package dbinterface
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Filter narrows List and Find down to the terms of a deck and/or the terms
+// matching a tag expression. Zero fields do not filter.
+type Filter struct {
+	Deck string
+	// Radical and Component match character cards by their structure.
+	Radical   string
+	Component string
+	// Tags is a tag expression built from tag names, and, or, not and
+	// parentheses, e.g. `HSK3 and not ("textbook ch.5" or work)`. Tag names
+	// containing spaces or clashing with an operator are double quoted.
+	Tags string
+	// NoLeeches leaves out cards flagged as leeches.
+	NoLeeches bool
+}
+
+type tagToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeTags(expr string) ([]tagToken, error) {
+	var tokens []tagToken
+	runes := []rune(expr)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+		case r == '(' || r == ')':
+			tokens = append(tokens, tagToken{text: string(r)})
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &ErrInvalidFilter{expr: expr, reason: "unterminated quote"}
+			}
+			tokens = append(tokens, tagToken{text: string(runes[i+1 : end]), quoted: true})
+			i = end
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()"`, runes[end]) {
+				end++
+			}
+			tokens = append(tokens, tagToken{text: string(runes[i:end])})
+			i = end - 1
+		}
+	}
+	return tokens, nil
+}
+
+// tagParser turns a tag expression into an SQL condition. Each tag becomes
+// tagCondition with the tag name as its argument.
+type tagParser struct {
+	expr         string
+	tokens       []tagToken
+	pos          int
+	tagCondition string
+	args         []any
+}
+
+func (p *tagParser) peekKeyword(keyword string) bool {
+	if p.pos >= len(p.tokens) || p.tokens[p.pos].quoted {
+		return false
+	}
+	return strings.EqualFold(p.tokens[p.pos].text, keyword)
+}
+
+func (p *tagParser) or() (string, error) {
+	left, err := p.and()
+	if err != nil {
+		return "", err
+	}
+	for p.peekKeyword("or") {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("(%s OR %s)", left, right)
+	}
+	return left, nil
+}
+
+func (p *tagParser) and() (string, error) {
+	left, err := p.not()
+	if err != nil {
+		return "", err
+	}
+	for p.peekKeyword("and") {
+		p.pos++
+		right, err := p.not()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("(%s AND %s)", left, right)
+	}
+	return left, nil
+}
+
+func (p *tagParser) not() (string, error) {
+	if p.peekKeyword("not") {
+		p.pos++
+		inner, err := p.not()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("NOT %s", inner), nil
+	}
+	return p.primary()
+}
+
+func (p *tagParser) primary() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", &ErrInvalidFilter{expr: p.expr, reason: "expected a tag"}
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	if !token.quoted && token.text == "(" {
+		inner, err := p.or()
+		if err != nil {
+			return "", err
+		}
+		if p.pos >= len(p.tokens) || p.tokens[p.pos].text != ")" {
+			return "", &ErrInvalidFilter{expr: p.expr, reason: "missing )"}
+		}
+		p.pos++
+		return inner, nil
+	}
+	if !token.quoted && (token.text == ")" || p.isKeyword(token.text)) {
+		return "", &ErrInvalidFilter{expr: p.expr, reason: fmt.Sprintf("unexpected %q", token.text)}
+	}
+	p.args = append(p.args, token.text)
+	return p.tagCondition, nil
+}
+
+func (p *tagParser) isKeyword(text string) bool {
+	for _, keyword := range []string{"and", "or", "not"} {
+		if strings.EqualFold(text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTags returns the SQL condition and arguments for a tag expression.
+func parseTags(expr, tagCondition string) (string, []any, error) {
+	tokens, err := tokenizeTags(expr)
+	if err != nil {
+		return "", nil, err
+	}
+	p := &tagParser{expr: expr, tokens: tokens, tagCondition: tagCondition}
+	cond, err := p.or()
+	if err != nil {
+		return "", nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return "", nil, &ErrInvalidFilter{expr: expr, reason: fmt.Sprintf("unexpected %q", p.tokens[p.pos].text)}
+	}
+	return cond, p.args, nil
+}
@@ -0,0 +1,62 @@
+package dbinterface
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseTags(t *testing.T) {
+	type args struct {
+		expr     string
+		wantCond string
+		wantArgs []any
+		wantErr  bool
+	}
+	tests := map[string]args{
+		"single tag": {
+			expr:     "HSK3",
+			wantCond: "T",
+			wantArgs: []any{"HSK3"},
+		},
+		"precedence": {
+			expr:     `HSK3 or "textbook ch.5" and not work`,
+			wantCond: "(T OR (T AND NOT T))",
+			wantArgs: []any{"HSK3", "textbook ch.5", "work"},
+		},
+		"parentheses": {
+			expr:     "(HSK3 OR HSK4) and not(work)",
+			wantCond: "((T OR T) AND NOT T)",
+			wantArgs: []any{"HSK3", "HSK4", "work"},
+		},
+		"quoted keyword": {
+			expr:     `"and"`,
+			wantCond: "T",
+			wantArgs: []any{"and"},
+		},
+		"missing operand": {
+			expr:    "HSK3 and",
+			wantErr: true,
+		},
+		"unbalanced": {
+			expr:    "(HSK3",
+			wantErr: true,
+		},
+		"unterminated quote": {
+			expr:    `"HSK3`,
+			wantErr: true,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			cond, args, err := parseTags(test.expr, "T")
+			var invalid *ErrInvalidFilter
+			if test.wantErr != errors.As(err, &invalid) {
+				t.Fatalf("Got error %v, wanted error: %v", err, test.wantErr)
+			}
+			if cond != test.wantCond || !reflect.DeepEqual(args, test.wantArgs) {
+				t.Errorf("Got %q %v; wanted %q %v", cond, args, test.wantCond, test.wantArgs)
+			}
+		})
+	}
+}
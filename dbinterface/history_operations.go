@@ -0,0 +1,53 @@
+package dbinterface
+
+import (
+	"context"
+	"time"
+)
+
+// HistoryKind is what happened to a card.
+type HistoryKind string
+
+const (
+	HistoryAdded           HistoryKind = "added"
+	HistoryRestored        HistoryKind = "restored"
+	HistoryEdited          HistoryKind = "edited"
+	HistoryDeleted         HistoryKind = "deleted"
+	HistoryPurged          HistoryKind = "purged"
+	HistoryUndone          HistoryKind = "undone"
+	HistoryRedone          HistoryKind = "redone"
+	HistoryReviewed        HistoryKind = "reviewed"
+	HistoryTagged          HistoryKind = "tagged"
+	HistoryUntagged        HistoryKind = "untagged"
+	HistoryAddedToDeck     HistoryKind = "added to deck"
+	HistoryRemovedFromDeck HistoryKind = "removed from deck"
+	HistoryPinned          HistoryKind = "pinned sentence"
+	HistoryCloze           HistoryKind = "cloze added"
+	HistoryLeech           HistoryKind = "leech"
+	HistoryReset           HistoryKind = "leech reset"
+)
+
+// HistoryEvent is one entry of a card's history.
+type HistoryEvent struct {
+	TermId int64
+	Kind   HistoryKind
+	Detail string
+	At     time.Time
+}
+
+// History returns everything that happened to the cards for term, oldest
+// first. The history is never deleted, so it outlives purged cards and
+// covers every card the term has had.
+func History(ctx context.Context, dbc *DatabaseConn, term string) ([]HistoryEvent, error) {
+	if err := verifyLanguage(dbc.language, term); err != nil {
+		return nil, err
+	}
+	return dbc.listHistory(ctx, term)
+}
+
+func verdict(correct bool) string {
+	if correct {
+		return "correct"
+	}
+	return "wrong"
+}
@@ -0,0 +1,41 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+)
+
+// addHistory appends an event to the history of each of the terms with the
+// given ids, including terms in the trash.
+func (dbc *DatabaseConn) addHistory(ctx context.Context, ids []int64, kind HistoryKind, detail string) error {
+	exec := fmt.Sprintf("INSERT INTO %s (user_id, term_id, term, kind, detail) SELECT user_id, id, term, ?, ? FROM %s WHERE id = ? AND user_id = ?",
+		dbc.table("history"), dbc.tableName)
+	for _, id := range ids {
+		if _, err := dbc.db.ExecContext(ctx, exec, kind, detail, id, dbc.userId); err != nil {
+			return fmt.Errorf("addHistory: %w", err)
+		}
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) listHistory(ctx context.Context, term string) ([]HistoryEvent, error) {
+	query := fmt.Sprintf("SELECT term_id, kind, detail, happened_at FROM %s WHERE user_id = ? AND term = ? ORDER BY id", dbc.table("history"))
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, term)
+	if err != nil {
+		return nil, fmt.Errorf("listHistory: %w", err)
+	}
+	defer rows.Close()
+
+	var events []HistoryEvent
+	for rows.Next() {
+		var event HistoryEvent
+		if err := rows.Scan(&event.TermId, &event.Kind, &event.Detail, &event.At); err != nil {
+			return nil, fmt.Errorf("listHistory: %w", err)
+		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listHistory: %w", err)
+	}
+	return events, nil
+}
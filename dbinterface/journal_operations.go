@@ -0,0 +1,111 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+)
+
+// JournalOp is the kind of a journaled operation.
+type JournalOp string
+
+const (
+	JournalAdd     JournalOp = "add"
+	JournalDelete  JournalOp = "delete"
+	JournalEdit    JournalOp = "edit"
+	JournalImport  JournalOp = "import"
+	JournalRestore JournalOp = "restore"
+)
+
+// JournalEntry is an operation recorded so that it can be undone. An add
+// lists every card it created, including those for the characters of a
+// word, and an edit keeps the definition it replaced. An import from the
+// library is an add whose last card, the word itself, was also given the
+// shared definition New in place of Old. A restore lists the cards taken
+// out of the trash. Purges are not journaled, as they cannot be undone.
+type JournalEntry struct {
+	Id    int64
+	Op    JournalOp
+	Terms []string
+	Ids   []int64
+	Old   string
+	New   string
+}
+
+// Undo reverses the last n operations that have not been undone, newest
+// first, and returns them. Added cards go to the trash, deleted cards are
+// restored and edits are reverted. Imported cards get their old definition
+// back and go to the trash, and restored cards go back to the trash.
+func Undo(ctx context.Context, dbc *DatabaseConn, n int) ([]JournalEntry, error) {
+	return replay(ctx, dbc, n, true)
+}
+
+// Redo applies the last n undone operations again, in the order they were
+// first made, and returns them.
+func Redo(ctx context.Context, dbc *DatabaseConn, n int) ([]JournalEntry, error) {
+	return replay(ctx, dbc, n, false)
+}
+
+func replay(ctx context.Context, dbc *DatabaseConn, n int, undo bool) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	err := dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		var err error
+		if entries, err = tx.journalEntries(ctx, !undo, n); err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := tx.apply(ctx, entry, undo); err != nil {
+				return err
+			}
+			if err := tx.markUndone(ctx, entry.Id, undo); err != nil {
+				return err
+			}
+			kind := HistoryRedone
+			if undo {
+				kind = HistoryUndone
+			}
+			if err := tx.addHistory(ctx, entry.Ids, kind, string(entry.Op)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// apply reverses entry if undo is set and makes it again otherwise.
+func (dbc *DatabaseConn) apply(ctx context.Context, entry JournalEntry, undo bool) error {
+	switch entry.Op {
+	case JournalAdd, JournalRestore:
+		if undo {
+			return dbc.trashTerms(ctx, entry.Ids)
+		}
+		return dbc.restoreTerms(ctx, entry.Ids)
+	case JournalDelete:
+		if undo {
+			return dbc.restoreTerms(ctx, entry.Ids)
+		}
+		return dbc.trashTerms(ctx, entry.Ids)
+	case JournalEdit:
+		definition := entry.New
+		if undo {
+			definition = entry.Old
+		}
+		return dbc.updateDefinition(ctx, entry.Ids, definition)
+	case JournalImport:
+		word := entry.Ids[len(entry.Ids)-1:]
+		if undo {
+			if err := dbc.updateDefinition(ctx, word, entry.Old); err != nil {
+				return err
+			}
+			return dbc.trashTerms(ctx, entry.Ids)
+		}
+		if err := dbc.restoreTerms(ctx, entry.Ids); err != nil {
+			return err
+		}
+		return dbc.updateDefinition(ctx, word, entry.New)
+	}
+	return fmt.Errorf("apply: unknown operation %q", entry.Op)
+}
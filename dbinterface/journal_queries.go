@@ -0,0 +1,92 @@
+package dbinterface
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// journalData is the part of a journal entry stored as JSON.
+type journalData struct {
+	Terms []string `json:"terms"`
+	Ids   []int64  `json:"ids"`
+	Old   string   `json:"old,omitempty"`
+	New   string   `json:"new,omitempty"`
+}
+
+// addJournalEntry records an operation. Operations that were undone can no
+// longer be redone once a new one is recorded.
+func (dbc *DatabaseConn) addJournalEntry(ctx context.Context, entry JournalEntry) error {
+	exec := fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND undone", dbc.table("journal"))
+	if _, err := dbc.db.ExecContext(ctx, exec, dbc.userId); err != nil {
+		return fmt.Errorf("addJournalEntry: %w", err)
+	}
+	data, err := json.Marshal(journalData{Terms: entry.Terms, Ids: entry.Ids, Old: entry.Old, New: entry.New})
+	if err != nil {
+		return fmt.Errorf("addJournalEntry: %w", err)
+	}
+	exec = fmt.Sprintf("INSERT INTO %s (user_id, op, data) VALUES (?, ?, ?)", dbc.table("journal"))
+	if _, err := dbc.db.ExecContext(ctx, exec, dbc.userId, entry.Op, data); err != nil {
+		return fmt.Errorf("addJournalEntry: %w", err)
+	}
+	return nil
+}
+
+// forgetJournalEntries drops the entries that can no longer be replayed once
+// ids are purged: those listing one of the ids, and every undone entry.
+func (dbc *DatabaseConn) forgetJournalEntries(ctx context.Context, ids []int64) error {
+	exec := fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND undone", dbc.table("journal"))
+	if _, err := dbc.db.ExecContext(ctx, exec, dbc.userId); err != nil {
+		return fmt.Errorf("forgetJournalEntries: %w", err)
+	}
+	exec = fmt.Sprintf("DELETE FROM %s WHERE user_id = ? AND JSON_CONTAINS(data, ?, '$.ids')", dbc.table("journal"))
+	for _, id := range ids {
+		if _, err := dbc.db.ExecContext(ctx, exec, dbc.userId, strconv.FormatInt(id, 10)); err != nil {
+			return fmt.Errorf("forgetJournalEntries: %w", err)
+		}
+	}
+	return nil
+}
+
+// journalEntries returns up to n entries that are undone or not, newest
+// first for entries to undo and oldest first for entries to redo.
+func (dbc *DatabaseConn) journalEntries(ctx context.Context, undone bool, n int) ([]JournalEntry, error) {
+	order := "DESC"
+	if undone {
+		order = "ASC"
+	}
+	query := fmt.Sprintf("SELECT id, op, data FROM %s WHERE user_id = ? AND undone = ? ORDER BY id %s LIMIT ? FOR UPDATE", dbc.table("journal"), order)
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, undone, n)
+	if err != nil {
+		return nil, fmt.Errorf("journalEntries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []JournalEntry
+	for rows.Next() {
+		var entry JournalEntry
+		var data []byte
+		if err := rows.Scan(&entry.Id, &entry.Op, &data); err != nil {
+			return nil, fmt.Errorf("journalEntries: %w", err)
+		}
+		var d journalData
+		if err := json.Unmarshal(data, &d); err != nil {
+			return nil, fmt.Errorf("journalEntries: %w", err)
+		}
+		entry.Terms, entry.Ids, entry.Old, entry.New = d.Terms, d.Ids, d.Old, d.New
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("journalEntries: %w", err)
+	}
+	return entries, nil
+}
+
+func (dbc *DatabaseConn) markUndone(ctx context.Context, id int64, undone bool) error {
+	exec := fmt.Sprintf("UPDATE %s SET undone = ? WHERE id = ? AND user_id = ?", dbc.table("journal"))
+	if _, err := dbc.db.ExecContext(ctx, exec, undone, id, dbc.userId); err != nil {
+		return fmt.Errorf("markUndone: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/flashcards/dict"
+	"github.com/flashcards/stats"
+)
+
+// DefaultLeechThreshold is the number of lapses that makes a card a leech
+// unless SetLeechThreshold is called.
+const DefaultLeechThreshold = 8
+
+// Leech is a card that keeps being failed, with suggestions for making it
+// easier to learn.
+type Leech struct {
+	Term        string
+	Lapses      int
+	Suggestions []string
+}
+
+// SetLeechThreshold sets how many failed reviews make a card a leech.
+func SetLeechThreshold(dbc *DatabaseConn, lapses int) {
+	dbc.leechThreshold.Store(int64(lapses))
+}
+
+// WithoutLeeches narrows filter to cards that are not leeches. Leeches are
+// left out of quizzes and reviews until they are reset.
+func WithoutLeeches(filter Filter) Filter {
+	filter.NoLeeches = true
+	return filter
+}
+
+// recordReview records a graded review of a term, described by detail in its
+// history. Each failed review is a lapse, and the term is flagged as a leech
+// once it reaches the threshold.
+func (dbc *DatabaseConn) recordReview(ctx context.Context, termId int64, correct bool, detail string) error {
+	if err := dbc.logReview(ctx, termId, correct, detail); err != nil {
+		return err
+	}
+	if correct {
+		return nil
+	}
+	lapses, err := dbc.addLapse(ctx, termId)
+	if err != nil {
+		return err
+	}
+	if threshold := dbc.leechThreshold.Load(); threshold <= 0 || int64(lapses) < threshold {
+		return nil
+	}
+	if err := dbc.markLeech(ctx, termId); err != nil {
+		return err
+	}
+	return dbc.addHistory(ctx, []int64{termId}, HistoryLeech, fmt.Sprintf("after %d lapses", lapses))
+}
+
+// logReview records a graded review of a term in its statistics and its
+// history without counting it towards the term's lapses.
+func (dbc *DatabaseConn) logReview(ctx context.Context, termId int64, correct bool, detail string) error {
+	if err := dbc.addEvent(ctx, termId, stats.Reviewed, correct); err != nil {
+		return err
+	}
+	return dbc.addHistory(ctx, []int64{termId}, HistoryReviewed, detail)
+}
+
+// Leeches returns the cards flagged as leeches with suggestions for each.
+func Leeches(ctx context.Context, dbc *DatabaseConn) (map[int64]Leech, error) {
+	leeches, err := dbc.listLeeches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for id, leech := range leeches {
+		pinned, err := dbc.pinnedSentences(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(pinned) == 0 {
+			leech.Suggestions = append(leech.Suggestions, "pin an example sentence or make a cloze card from one")
+		}
+		if tokens := dbc.language.Tokenize(leech.Term); len(tokens) > 1 {
+			leech.Suggestions = append(leech.Suggestions,
+				fmt.Sprintf("split it into %s and learn those first", strings.Join(tokens, ", ")))
+		}
+		leech.Suggestions = append(leech.Suggestions, "rewrite the definition in your own words")
+		leeches[id] = leech
+	}
+	return leeches, nil
+}
+
+// SplitLeech makes sure every part of a leech is a card of its own, the same
+// way Add does for new words, and returns the ids of the cards it added.
+func SplitLeech(ctx context.Context, dbc *DatabaseConn, term string, dictionary dict.Dictionary) ([]int64, error) {
+	return Add(ctx, dbc, term, dictionary)
+}
+
+// ResetLeech clears a card's lapses and returns it to quizzes.
+func ResetLeech(ctx context.Context, dbc *DatabaseConn, term string) error {
+	if err := verifyLanguage(dbc.language, term); err != nil {
+		return err
+	}
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		termIds, err := tx.findTerm(ctx, term)
+		if err != nil {
+			return err
+		}
+		if err := tx.resetLapses(ctx, termIds); err != nil {
+			return err
+		}
+		return tx.addHistory(ctx, termIds, HistoryReset, "")
+	})
+}
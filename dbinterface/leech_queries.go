@@ -0,0 +1,70 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+)
+
+// addLapse counts a failed review of a term and returns its lapse count.
+func (dbc *DatabaseConn) addLapse(ctx context.Context, termId int64) (int, error) {
+	exec := fmt.Sprintf("INSERT INTO %s (term_id, lapses) VALUES (?, 1) ON DUPLICATE KEY UPDATE lapses = lapses + 1", dbc.table("lapses"))
+	if _, err := dbc.db.ExecContext(ctx, exec, termId); err != nil {
+		return 0, fmt.Errorf("addLapse: %w", err)
+	}
+	var lapses int
+	query := fmt.Sprintf("SELECT lapses FROM %s WHERE term_id = ?", dbc.table("lapses"))
+	if err := dbc.db.QueryRowContext(ctx, query, termId).Scan(&lapses); err != nil {
+		return 0, fmt.Errorf("addLapse: %w", err)
+	}
+	return lapses, nil
+}
+
+func (dbc *DatabaseConn) markLeech(ctx context.Context, termId int64) error {
+	exec := fmt.Sprintf("UPDATE %s SET leech = TRUE WHERE term_id = ?", dbc.table("lapses"))
+	if _, err := dbc.db.ExecContext(ctx, exec, termId); err != nil {
+		return fmt.Errorf("markLeech: %w", err)
+	}
+	return nil
+}
+
+// notLeech is a condition on a term aliased t that holds unless the term is
+// flagged as a leech.
+func (dbc *DatabaseConn) notLeech() string {
+	return fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s l WHERE l.term_id = t.id AND l.leech)", dbc.table("lapses"))
+}
+
+func (dbc *DatabaseConn) resetLapses(ctx context.Context, termIds []int64) error {
+	exec := fmt.Sprintf("DELETE FROM %s WHERE term_id = ?", dbc.table("lapses"))
+	for _, termId := range termIds {
+		if _, err := dbc.db.ExecContext(ctx, exec, termId); err != nil {
+			return fmt.Errorf("resetLapses: %w", err)
+		}
+	}
+	return nil
+}
+
+// listLeeches returns the terms flagged as leeches with their lapse counts.
+func (dbc *DatabaseConn) listLeeches(ctx context.Context) (map[int64]Leech, error) {
+	query := fmt.Sprintf(`SELECT t.id, t.term, l.lapses FROM %s t
+		JOIN %s l ON l.term_id = t.id
+		WHERE t.user_id = ? AND t.language = ? AND l.leech AND t.deleted_at IS NULL`, dbc.tableName, dbc.table("lapses"))
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, dbc.language.Name())
+	if err != nil {
+		return nil, fmt.Errorf("listLeeches: %w", err)
+	}
+	defer rows.Close()
+
+	leeches := make(map[int64]Leech)
+	for rows.Next() {
+		var id int64
+		var leech Leech
+		if err := rows.Scan(&id, &leech.Term, &leech.Lapses); err != nil {
+			return nil, fmt.Errorf("listLeeches: %w", err)
+		}
+		leeches[id] = leech
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listLeeches: %w", err)
+	}
+	return leeches, nil
+}
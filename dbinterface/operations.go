@@ -1,13 +1,17 @@
 package dbinterface
 
 import (
+	"context"
 	"database/sql"
-	"errors"
+	"fmt"
 	"log"
-	"unicode"
-	"unicode/utf8"
+	"sync/atomic"
+	"time"
 
+	"github.com/flashcards/database"
 	"github.com/flashcards/dict"
+	"github.com/flashcards/language"
+	"github.com/flashcards/stats"
 	"github.com/go-sql-driver/mysql"
 )
 
@@ -16,121 +20,246 @@ type TermDef struct {
 	Definition string
 }
 
-func Connect(cfg mysql.Config, tableName string) (*DatabaseConn, error) {
+func Connect(ctx context.Context, cfg mysql.Config, tableName string) (*DatabaseConn, error) {
+	return ConnectWithLanguage(ctx, cfg, tableName, language.Chinese{})
+}
+
+// ConnectWithLanguage connects to a table of cards and works on those for
+// lang. Every card records the language it was added for: terms are validated
+// against lang's script when they are added, deleted or searched, and only
+// cards of lang are found and listed. Cards of other languages are kept as
+// they are and come back with a connection for their language.
+//
+// The connection works on the cards of DefaultUser. ForUser gives one for
+// another user.
+//
+// Every operation on the connection takes a context. Cancelling it, or
+// letting its deadline pass, stops the queries of the operation and rolls
+// back its transaction.
+func ConnectWithLanguage(ctx context.Context, cfg mysql.Config, tableName string, lang language.Language) (*DatabaseConn, error) {
+	cfg.ParseTime = true
+	cfg.Loc = time.Local
 	db, err := sql.Open("mysql", cfg.FormatDSN())
 	if err != nil {
 		return &DatabaseConn{}, err
 	}
 
-	err = db.Ping()
+	err = db.PingContext(ctx)
 	if err != nil {
 		return &DatabaseConn{}, err
 	}
 	log.Println("Connected!")
 
 	databaseConn := &DatabaseConn{
-		db:           db,
-		tableName:    tableName,
-		sequenceGaps: make([]int64, 0),
+		db:             db,
+		conn:           db,
+		tableName:      tableName,
+		language:       lang,
+		leechThreshold: new(atomic.Int64),
+		userId:         database.DefaultUserId,
 	}
+	databaseConn.leechThreshold.Store(DefaultLeechThreshold)
 
-	if err = databaseConn.checkForGaps(); err != nil {
-		log.Printf("%v", err)
+	if err := database.Migrate(ctx, db, tableName); err != nil {
+		return &DatabaseConn{}, err
 	}
 	return databaseConn, nil
 }
 
-func verifyLanguage(term string) error {
-	for _, c := range term {
-		if !unicode.Is(unicode.Han, c) {
-			return &ErrUnexpectedLanguage{expectedLanguage: "Chinese", term: string(c)}
-		}
+func verifyLanguage(lang language.Language, term string) error {
+	if c, ok := language.Validate(lang, term); !ok {
+		return &ErrUnexpectedLanguage{expectedLanguage: lang.Name(), term: string(c)}
 	}
 	return nil
 }
 
-func addIfNotDuplicate(dbc *DatabaseConn, term string, dictMap dict.DictMap) (*int64, error) {
-	foundId, err := dbc.findTerm(term)
-	var notFound *ErrNotFound
-	if !errors.As(err, &notFound) && err != nil {
-		return nil, err
-	}
-	if len(foundId) != 0 {
-		log.Printf("%q already exists at %v\n", term, foundId)
-		return nil, nil
-	}
-
-	def, inDict := dictMap.GetDefinition(term)
-	if !inDict {
-		log.Printf("%q not found in dictionary", term)
-	} else {
-		log.Printf("%q found in dictionary, definition: %q", term, def)
-	}
+// addIfNotDuplicate adds a term with its character data and HSK tag in one
+// transaction. A term in the trash is restored rather than added again,
+// which brings back its decks, tags and history. input is what the user
+// typed, which is the term itself or a word containing it.
+func addIfNotDuplicate(ctx context.Context, dbc *DatabaseConn, input, term string, dictionary dict.Dictionary) (*int64, error) {
+	def, inDict := dictionary.GetDefinition(term)
+	var added *int64
+	err := dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		id, status, err := tx.addTerm(ctx, term, def)
+		if err != nil {
+			return err
+		}
+		switch status {
+		case termExists:
+			log.Printf("%q already exists at %v\n", term, id)
+			return nil
+		case termRestored:
+			log.Printf("%q restored from the trash at %v\n", term, id)
+			added = &id
+			return tx.addHistory(ctx, []int64{id}, HistoryRestored, fmt.Sprintf("from input %q", input))
+		}
+		entry, _ := dictionary.Lookup(term)
+		detail := fmt.Sprintf("from input %q, not in dictionary", input)
+		if !inDict {
+			log.Printf("%q not found in dictionary", term)
+		} else {
+			log.Printf("%q found in dictionary, definition: %q", term, def)
+			detail = fmt.Sprintf("from input %q, definition %q", input, def)
+			if len(entry.Senses) > 0 {
+				detail += " from " + entry.Senses[0].Source
+			}
+		}
+		if err := tx.addHistory(ctx, []int64{id}, HistoryAdded, detail); err != nil {
+			return err
+		}
 
-	id, err := dbc.addTerm(term, def)
+		if err := tx.addEvent(ctx, id, stats.Added, false); err != nil {
+			return err
+		}
+		if entry.Decomposition != nil {
+			if err := tx.addCharacter(ctx, id, entry.Decomposition); err != nil {
+				return err
+			}
+		}
+		if entry.HSKLevel > 0 {
+			tagId, err := tx.findOrCreateTag(ctx, dict.HSKTag(entry.HSKLevel))
+			if err != nil {
+				return err
+			}
+			if err := tx.tagTerms(ctx, []int64{id}, tagId); err != nil {
+				return err
+			}
+		}
+		added = &id
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return &id, nil
+	return added, nil
 }
 
-func Add(dbc *DatabaseConn, term string, dictMap dict.DictMap) ([]int64, error) {
-	var addedIds []int64
-	err := verifyLanguage(term)
+// Add adds term and each of its tokens, such as the characters of a Chinese
+// word, that is not a card yet. It is all-or-nothing: if any of them cannot
+// be added, none are.
+func Add(ctx context.Context, dbc *DatabaseConn, term string, dictionary dict.Dictionary) ([]int64, error) {
+	err := verifyLanguage(dbc.language, term)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, c := range term {
-		id, err := addIfNotDuplicate(dbc, string(c), dictMap)
-		if err != nil {
-			return nil, err
-		}
-		if id != nil {
-			addedIds = append(addedIds, *id)
+	var entry JournalEntry
+	err = dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		var err error
+		if entry, err = tx.add(ctx, term, dictionary); err != nil || len(entry.Ids) == 0 {
+			return err
 		}
+		return tx.addJournalEntry(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return entry.Ids, nil
+}
 
-	if utf8.RuneCountInString(term) > 1 {
-		id, err := addIfNotDuplicate(dbc, term, dictMap)
+// add adds term and its tokens like Add without journaling it, and returns
+// the journal entry for the cards it added. The term itself comes last.
+func (dbc *DatabaseConn) add(ctx context.Context, term string, dictionary dict.Dictionary) (JournalEntry, error) {
+	tokens := dbc.language.Tokenize(term)
+	if len(tokens) != 1 || tokens[0] != term {
+		tokens = append(tokens, term)
+	}
+	entry := JournalEntry{Op: JournalAdd}
+	for _, token := range tokens {
+		id, err := addIfNotDuplicate(ctx, dbc, term, token, dictionary)
 		if err != nil {
-			return nil, err
+			return JournalEntry{}, err
 		}
 		if id != nil {
-			addedIds = append(addedIds, *id)
+			entry.Ids = append(entry.Ids, *id)
+			entry.Terms = append(entry.Terms, token)
 		}
 	}
-	return addedIds, nil
+	return entry, nil
 }
 
-func Delete(dbc *DatabaseConn, term string) error {
-	err := verifyLanguage(term)
+// Delete moves a term to the trash, from which it can be restored until it
+// is purged.
+func Delete(ctx context.Context, dbc *DatabaseConn, term string) error {
+	err := verifyLanguage(dbc.language, term)
 	if err != nil {
 		return err
 	}
 
-	err = dbc.deleteTerm(term)
-	if err != nil {
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		ids, err := tx.deleteTerm(ctx, term)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		if err := tx.addHistory(ctx, ids, HistoryDeleted, ""); err != nil {
+			return err
+		}
+		return tx.addJournalEntry(ctx, JournalEntry{Op: JournalDelete, Terms: []string{term}, Ids: ids})
+	})
+}
+
+// Edit replaces the definition of a term.
+func Edit(ctx context.Context, dbc *DatabaseConn, term, definition string) error {
+	if err := verifyLanguage(dbc.language, term); err != nil {
 		return err
 	}
-	return nil
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		entry, err := tx.edit(ctx, term, definition)
+		if err != nil {
+			return err
+		}
+		return tx.addJournalEntry(ctx, entry)
+	})
+}
+
+// edit replaces the definition of a term like Edit without journaling it,
+// and returns the journal entry for the edit.
+func (dbc *DatabaseConn) edit(ctx context.Context, term, definition string) (JournalEntry, error) {
+	terms, err := dbc.findAllTermsWithSubstring(ctx, term, Filter{})
+	if err != nil {
+		return JournalEntry{}, err
+	}
+	for id, termDef := range terms {
+		if termDef.Term != term {
+			continue
+		}
+		if err := dbc.updateDefinition(ctx, []int64{id}, definition); err != nil {
+			return JournalEntry{}, err
+		}
+		if err := dbc.addHistory(ctx, []int64{id}, HistoryEdited, fmt.Sprintf("%q to %q", termDef.Definition, definition)); err != nil {
+			return JournalEntry{}, err
+		}
+		return JournalEntry{Op: JournalEdit, Terms: []string{term}, Ids: []int64{id}, Old: termDef.Definition, New: definition}, nil
+	}
+	return JournalEntry{}, &ErrNotFound{term: term}
+}
+
+func Find(ctx context.Context, dbc *DatabaseConn, term string) (map[int64]TermDef, error) {
+	return FindFiltered(ctx, dbc, term, Filter{})
 }
 
-func Find(dbc *DatabaseConn, term string) (map[int64]TermDef, error) {
-	err := verifyLanguage(term)
+// FindFiltered finds the terms containing term that match filter.
+func FindFiltered(ctx context.Context, dbc *DatabaseConn, term string, filter Filter) (map[int64]TermDef, error) {
+	err := verifyLanguage(dbc.language, term)
 	if err != nil {
 		return nil, err
 	}
 
-	terms, err := dbc.findAllTermsWithSubstring(term)
+	terms, err := dbc.findAllTermsWithSubstring(ctx, term, filter)
 	if err != nil {
 		return nil, err
 	}
 	return terms, nil
 }
 
-func List(dbc *DatabaseConn) (map[int64]TermDef, error) {
-	listAll, err := dbc.listAll()
+func List(ctx context.Context, dbc *DatabaseConn) (map[int64]TermDef, error) {
+	return ListFiltered(ctx, dbc, Filter{})
+}
+
+// ListFiltered lists the terms matching filter.
+func ListFiltered(ctx context.Context, dbc *DatabaseConn, filter Filter) (map[int64]TermDef, error) {
+	listAll, err := dbc.listAll(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,38 @@
+package dbinterface
+
+import (
+	"sort"
+
+	"github.com/flashcards/dict"
+)
+
+// Order is the order List and Find results are shown in.
+type Order int
+
+const (
+	ById Order = iota
+	// ByFrequency puts the most frequent terms first. Terms missing from the
+	// frequency list come last, by id.
+	ByFrequency
+)
+
+// SortIds returns the ids of terms in the given order.
+func SortIds(terms map[int64]TermDef, order Order, dictionary dict.Dictionary) []int64 {
+	ids := make([]int64, 0, len(terms))
+	ranks := make(map[int64]int, len(terms))
+	for id, termDef := range terms {
+		ids = append(ids, id)
+		if order == ByFrequency {
+			entry, _ := dictionary.Lookup(termDef.Term)
+			ranks[id] = entry.Rank
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ri, rj := ranks[ids[i]], ranks[ids[j]]
+		if ri != rj {
+			return ri != 0 && (rj == 0 || ri < rj)
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
@@ -1,21 +1,39 @@
 package dbinterface
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/flashcards/language"
 )
 
+// DatabaseConn is safe for concurrent use by multiple goroutines.
 type DatabaseConn struct {
-	db           *sql.DB
-	tableName    string
-	sequenceGaps []int64
+	// db runs the queries. It is conn, or a transaction on it.
+	db querier
+	// conn is nil inside a transaction.
+	conn      *sql.DB
+	tableName string
+	// language is the language of the cards the connection works on.
+	// Terms are looked up among the cards of that language only.
+	language language.Language
+	// userId is the user whose cards the connection works on. Every query
+	// is scoped to it.
+	userId int64
+	// leechThreshold is the number of lapses that makes a card a leech, 0
+	// to never flag leeches. It is shared with transactions on the
+	// connection.
+	leechThreshold *atomic.Int64
 }
 
-func (dbc *DatabaseConn) findTerm(termToFind string) ([]int64, error) {
-	query := fmt.Sprintf("SELECT id FROM %s WHERE term='%s'", dbc.tableName, termToFind)
-	rows, err := dbc.db.Query(query)
+func (dbc *DatabaseConn) findTerm(ctx context.Context, termToFind string) ([]int64, error) {
+	query := fmt.Sprintf("SELECT id FROM %s WHERE user_id = ? AND language = ? AND term = ? AND deleted_at IS NULL", dbc.tableName)
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, dbc.language.Name(), termToFind)
 	if err != nil {
-		return nil, fmt.Errorf("findTerm %q: %v", termToFind, err)
+		return nil, fmt.Errorf("findTerm %q: %w", termToFind, err)
 	}
 	defer rows.Close()
 
@@ -23,12 +41,12 @@ func (dbc *DatabaseConn) findTerm(termToFind string) ([]int64, error) {
 	for rows.Next() {
 		var id int64
 		if err := rows.Scan(&id); err != nil {
-			return nil, fmt.Errorf("findTerm %q: %v", termToFind, err)
+			return nil, fmt.Errorf("findTerm %q: %w", termToFind, err)
 		}
 		ids = append(ids, id)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("findTerm %q: %v", termToFind, err)
+		return nil, fmt.Errorf("findTerm %q: %w", termToFind, err)
 	}
 
 	if len(ids) == 0 {
@@ -38,26 +56,24 @@ func (dbc *DatabaseConn) findTerm(termToFind string) ([]int64, error) {
 	return ids, nil
 }
 
-func (dbc *DatabaseConn) findAllTermsWithSubstring(termToFind string) (map[int64]TermDef, error) {
-	query := fmt.Sprintf("SELECT * FROM %s WHERE term LIKE '%%%s%%'", dbc.tableName, termToFind)
-	rows, err := dbc.db.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf("findAllTermsWithSubstring %q: %v", termToFind, err)
+// ownTerm returns an ErrNotFound unless the term with id termId belongs to
+// the user.
+func (dbc *DatabaseConn) ownTerm(ctx context.Context, termId int64) error {
+	var count int
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE id = ? AND user_id = ?", dbc.tableName)
+	if err := dbc.db.QueryRowContext(ctx, query, termId, dbc.userId).Scan(&count); err != nil {
+		return fmt.Errorf("ownTerm: %w", err)
 	}
-	defer rows.Close()
-
-	terms := make(map[int64]TermDef)
-	for rows.Next() {
-		var id int64
-		var term string
-		var def string
-		if err := rows.Scan(&id, &term, &def); err != nil {
-			return nil, fmt.Errorf("findAllTermsWithSubstring %q: %v", termToFind, err)
-		}
-		terms[id] = TermDef{Term: term, Definition: def}
+	if count == 0 {
+		return &ErrNotFound{term: fmt.Sprintf("card %d", termId)}
 	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("findAllTermsWithSubstring %q: %v", termToFind, err)
+	return nil
+}
+
+func (dbc *DatabaseConn) findAllTermsWithSubstring(ctx context.Context, termToFind string, filter Filter) (map[int64]TermDef, error) {
+	terms, err := dbc.selectTerms(ctx, filter, "t.term LIKE ?", "%"+termToFind+"%")
+	if err != nil {
+		return nil, fmt.Errorf("findAllTermsWithSubstring %q: %w", termToFind, err)
 	}
 
 	if len(terms) == 0 {
@@ -67,153 +83,236 @@ func (dbc *DatabaseConn) findAllTermsWithSubstring(termToFind string) (map[int64
 	return terms, nil
 }
 
-func (dbc *DatabaseConn) checkForGaps() error {
-	// if table is empty, return
-	empty, err := dbc.tableIsEmpty()
-	if err != nil {
-		return err
-	}
-	if empty {
-		return nil
-	}
-
-	gapQuery := fmt.Sprintf(`WITH RECURSIVE all_ids AS (
-		SELECT MIN(id) AS id
-		FROM %s
-		UNION ALL
-		SELECT id + 1
-		FROM all_ids
-		WHERE id + 1 <= (SELECT MAX(id) FROM %s)
-	),
-	existing_ids AS (
-		SELECT id FROM %s
-	)
-	SELECT a.id AS missing_id
-	FROM all_ids a
-	LEFT JOIN existing_ids e ON a.id = e.id
-	WHERE e.id IS NULL;
-	`, dbc.tableName, dbc.tableName, dbc.tableName)
-	rows, err := dbc.db.Query(gapQuery)
+// table returns the name of the table with the given suffix that belongs to
+// the terms table, e.g. terms_decks.
+func (dbc *DatabaseConn) table(suffix string) string {
+	return dbc.tableName + "_" + suffix
+}
+
+// selectTerms returns the user's terms in the connection's language matching
+// filter and the extra condition. Terms in the trash are left out.
+func (dbc *DatabaseConn) selectTerms(ctx context.Context, filter Filter, condition string, args ...any) (map[int64]TermDef, error) {
+	conditions := []string{"t.user_id = ?", "t.language = ?", "t.deleted_at IS NULL"}
+	args = append([]any{dbc.userId, dbc.language.Name()}, args...)
+	if condition != "" {
+		conditions = append(conditions, condition)
+	}
+	if filter.Deck != "" {
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (SELECT 1 FROM %s dt JOIN %s d ON d.id = dt.deck_id
+			WHERE dt.term_id = t.id AND d.user_id = t.user_id AND d.name = ?)`, dbc.table("deck_terms"), dbc.table("decks")))
+		args = append(args, filter.Deck)
+	}
+	if filter.Radical != "" {
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM %s c WHERE c.term_id = t.id AND c.radical = ?)", dbc.table("characters")))
+		args = append(args, filter.Radical)
+	}
+	if filter.Component != "" {
+		conditions = append(conditions, fmt.Sprintf("EXISTS (SELECT 1 FROM %s c WHERE c.term_id = t.id AND c.component = ?)", dbc.table("components")))
+		args = append(args, filter.Component)
+	}
+	if filter.Tags != "" {
+		tagCondition := fmt.Sprintf(`EXISTS (SELECT 1 FROM %s tt JOIN %s g ON g.id = tt.tag_id
+			WHERE tt.term_id = t.id AND g.user_id = t.user_id AND g.name = ?)`, dbc.table("term_tags"), dbc.table("tags"))
+		tagsCondition, tagArgs, err := parseTags(filter.Tags, tagCondition)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, tagsCondition)
+		args = append(args, tagArgs...)
+	}
+
+	if filter.NoLeeches {
+		conditions = append(conditions, dbc.notLeech())
+	}
+
+	query := fmt.Sprintf("SELECT t.id, t.term, t.definition FROM %s t WHERE %s", dbc.tableName, strings.Join(conditions, " AND "))
+	rows, err := dbc.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return fmt.Errorf("checkForGaps: %v", err)
+		return nil, err
 	}
 	defer rows.Close()
 
+	terms := make(map[int64]TermDef)
 	for rows.Next() {
-		var missingId int64
-		if err := rows.Scan(&missingId); err != nil {
-			return fmt.Errorf("checkForGaps: %v", err)
+		var id int64
+		var term string
+		var def string
+		if err := rows.Scan(&id, &term, &def); err != nil {
+			return nil, err
 		}
-		dbc.sequenceGaps = append(dbc.sequenceGaps, missingId)
+		terms[id] = TermDef{Term: term, Definition: def}
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("checkForGaps: %v", err)
-	}
-	if len(dbc.sequenceGaps) > 0 {
-		fmt.Printf("Found gaps %v\n", dbc.sequenceGaps)
+		return nil, err
 	}
-	return nil
+	return terms, nil
 }
 
-func (dbc *DatabaseConn) addTerm(term string, definition string) (int64, error) {
-	var exec string
-	var insertId int64
-	if len(dbc.sequenceGaps) > 0 {
-		fmt.Println("Inserting into sequence gap")
-		insertId = dbc.sequenceGaps[0]
-		exec = fmt.Sprintf("INSERT INTO %s (id, term, definition) VALUES (%d, '%s', '%s')", dbc.tableName, insertId, term, definition)
-	} else {
-		exec = fmt.Sprintf("INSERT INTO %s (term, definition) VALUES ('%s', '%s')", dbc.tableName, term, definition)
-	}
-	result, err := dbc.db.Exec(exec)
+// termStatus is what addTerm found for a term.
+type termStatus int
+
+const (
+	termAdded termStatus = iota
+	termRestored
+	termExists
+)
+
+// addTerm inserts a term unless it is already there, in which case it is
+// taken out of the trash if needed. It is a single statement relying on the
+// unique key on the user, language and term, so concurrent clients cannot add the same term twice.
+func (dbc *DatabaseConn) addTerm(ctx context.Context, term string, definition string) (int64, termStatus, error) {
+	exec := fmt.Sprintf(`INSERT INTO %s (user_id, language, term, definition) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE id = LAST_INSERT_ID(id), deleted_at = NULL`, dbc.tableName)
+	result, err := dbc.db.ExecContext(ctx, exec, dbc.userId, dbc.language.Name(), term, definition)
 	if err != nil {
-		return 0, fmt.Errorf("addTerm: %v", err)
+		return 0, 0, fmt.Errorf("addTerm: %w", err)
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
-		return 0, fmt.Errorf("addTerm: %v", err)
+		return 0, 0, fmt.Errorf("addTerm: %w", err)
+	}
+	// MySQL reports 1 affected row for an insert, 2 for an update that
+	// changed the row and 0 for one that did not.
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, 0, fmt.Errorf("addTerm: %w", err)
+	}
+	switch affected {
+	case 1:
+		fmt.Printf("Added %q\n", term)
+		return id, termAdded, nil
+	case 2:
+		return id, termRestored, nil
 	}
+	return id, termExists, nil
+}
 
-	if insertId != 0 {
-		if id != insertId {
-			fmt.Printf("WARNING: inserted at %d, expected to insert at %d", id, insertId)
-		} else {
-			dbc.sequenceGaps = dbc.sequenceGaps[1:]
+func (dbc *DatabaseConn) updateDefinition(ctx context.Context, ids []int64, definition string) error {
+	exec := fmt.Sprintf("UPDATE %s SET definition = ? WHERE id = ? AND user_id = ?", dbc.tableName)
+	for _, id := range ids {
+		if _, err := dbc.db.ExecContext(ctx, exec, definition, id, dbc.userId); err != nil {
+			return fmt.Errorf("updateDefinition: %w", err)
 		}
 	}
-
-	fmt.Printf("Added %q\n", term)
-	return id, nil
+	return nil
 }
 
-func (dbc *DatabaseConn) deleteTerm(term string) error {
-	ids, err := dbc.findTerm(term)
+// deleteTerm moves a term to the trash and returns the ids it moved. Its row
+// and everything linked to it stay in place so that it can be restored.
+func (dbc *DatabaseConn) deleteTerm(ctx context.Context, term string) ([]int64, error) {
+	ids, err := dbc.findTerm(ctx, term)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if len(ids) == 0 {
 		fmt.Printf("Term %q does not exist in database\n", term)
-		return nil
+		return nil, nil
 	}
-	query := fmt.Sprintf("DELETE FROM %s WHERE term='%s'", dbc.tableName, term)
-	result, err := dbc.db.Exec(query)
+	exec := fmt.Sprintf("UPDATE %s SET deleted_at = NOW() WHERE user_id = ? AND language = ? AND term = ? AND deleted_at IS NULL", dbc.tableName)
+	result, err := dbc.db.ExecContext(ctx, exec, dbc.userId, dbc.language.Name(), term)
 	if err != nil {
-		return fmt.Errorf("deleteTerm: %v", err)
+		return nil, fmt.Errorf("deleteTerm: %w", err)
 	}
 
 	num, err := result.RowsAffected()
 	if err != nil {
-		return fmt.Errorf("deleteTerm: %v", err)
+		return nil, fmt.Errorf("deleteTerm: %w", err)
 	}
 	if num != int64(len(ids)) {
 		fmt.Printf("WARNING: %d number of rows deleted, expected %d", num, len(ids))
 	} else {
-		fmt.Printf("Deleted %q in rows %v\n", term, ids)
-		dbc.sequenceGaps = append(dbc.sequenceGaps, ids...)
+		fmt.Printf("Moved %q in rows %v to the trash\n", term, ids)
+	}
+	return ids, nil
+}
+
+func (dbc *DatabaseConn) trashTerms(ctx context.Context, ids []int64) error {
+	exec := fmt.Sprintf("UPDATE %s SET deleted_at = NOW() WHERE id = ? AND user_id = ? AND deleted_at IS NULL", dbc.tableName)
+	for _, id := range ids {
+		if _, err := dbc.db.ExecContext(ctx, exec, id, dbc.userId); err != nil {
+			return fmt.Errorf("trashTerms: %w", err)
+		}
 	}
 	return nil
 }
 
-func (dbc *DatabaseConn) listAll() (map[int64]TermDef, error) {
-	rows, err := dbc.db.Query(fmt.Sprintf("SELECT * from %s", dbc.tableName))
+func (dbc *DatabaseConn) findTrashed(ctx context.Context, term string) ([]int64, error) {
+	query := fmt.Sprintf("SELECT id FROM %s WHERE user_id = ? AND language = ? AND term = ? AND deleted_at IS NOT NULL", dbc.tableName)
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, dbc.language.Name(), term)
 	if err != nil {
-		return nil, fmt.Errorf("listAll: %v", err)
+		return nil, fmt.Errorf("findTrashed %q: %w", term, err)
 	}
 	defer rows.Close()
 
-	allTerms := make(map[int64]TermDef)
+	var ids []int64
 	for rows.Next() {
 		var id int64
-		var term string
-		var def string
-		if err := rows.Scan(&id, &term, &def); err != nil {
-			return nil, fmt.Errorf("listAll: %v", err)
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("findTrashed %q: %w", term, err)
 		}
-		allTerms[id] = TermDef{Term: term, Definition: def}
+		ids = append(ids, id)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("listAll: %v", err)
+		return nil, fmt.Errorf("findTrashed %q: %w", term, err)
 	}
-	return allTerms, nil
+	if len(ids) == 0 {
+		return nil, &ErrNotFound{term: term}
+	}
+	return ids, nil
+}
+
+func (dbc *DatabaseConn) restoreTerms(ctx context.Context, ids []int64) error {
+	exec := fmt.Sprintf("UPDATE %s SET deleted_at = NULL WHERE id = ? AND user_id = ?", dbc.tableName)
+	for _, id := range ids {
+		if _, err := dbc.db.ExecContext(ctx, exec, id, dbc.userId); err != nil {
+			return fmt.Errorf("restoreTerms: %w", err)
+		}
+	}
+	return nil
+}
+
+// purgeTerms deletes trashed terms for good, along with everything linked to
+// them.
+func (dbc *DatabaseConn) purgeTerms(ctx context.Context, ids []int64) error {
+	exec := fmt.Sprintf("DELETE FROM %s WHERE id = ? AND user_id = ? AND deleted_at IS NOT NULL", dbc.tableName)
+	for _, id := range ids {
+		if _, err := dbc.db.ExecContext(ctx, exec, id, dbc.userId); err != nil {
+			return fmt.Errorf("purgeTerms: %w", err)
+		}
+	}
+	if err := dbc.deleteLinks(ctx, ids); err != nil {
+		return fmt.Errorf("purgeTerms: %w", err)
+	}
+	return nil
 }
 
-func (dbc *DatabaseConn) tableIsEmpty() (bool, error) {
-	rows, err := dbc.db.Query(fmt.Sprintf("SELECT id FROM %s LIMIT 1", dbc.tableName))
+func (dbc *DatabaseConn) listTrash(ctx context.Context) (map[int64]TrashedTerm, error) {
+	query := fmt.Sprintf("SELECT id, term, definition, deleted_at FROM %s WHERE user_id = ? AND language = ? AND deleted_at IS NOT NULL", dbc.tableName)
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, dbc.language.Name())
 	if err != nil {
-		return true, err
+		return nil, fmt.Errorf("listTrash: %w", err)
 	}
 	defer rows.Close()
 
-	empty := true
+	trash := make(map[int64]TrashedTerm)
 	for rows.Next() {
 		var id int64
-		if err := rows.Scan(&id); err != nil {
-			return empty, err
+		var trashed TrashedTerm
+		if err := rows.Scan(&id, &trashed.Term, &trashed.Definition, &trashed.DeletedAt); err != nil {
+			return nil, fmt.Errorf("listTrash: %w", err)
 		}
-		empty = false
+		trash[id] = trashed
 	}
 	if err := rows.Err(); err != nil {
-		return empty, fmt.Errorf("tableIsEmpty: %v", err)
+		return nil, fmt.Errorf("listTrash: %w", err)
 	}
-	return empty, nil
+	return trash, nil
+}
+
+func (dbc *DatabaseConn) listAll(ctx context.Context, filter Filter) (map[int64]TermDef, error) {
+	allTerms, err := dbc.selectTerms(ctx, filter, "")
+	if err != nil {
+		return nil, fmt.Errorf("listAll: %w", err)
+	}
+	return allTerms, nil
 }
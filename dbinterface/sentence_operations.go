@@ -0,0 +1,51 @@
+package dbinterface
+
+import (
+	"context"
+	"github.com/flashcards/sentences"
+)
+
+// PinSentence attaches a sentence to a card so it is always shown with it.
+func PinSentence(ctx context.Context, dbc *DatabaseConn, term string, sentence sentences.Sentence) error {
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		ids, err := tx.findTerm(ctx, term)
+		if err != nil {
+			return err
+		}
+		if err := tx.pinSentence(ctx, ids[0], sentence); err != nil {
+			return err
+		}
+		return tx.addHistory(ctx, ids[:1], HistoryPinned, sentence.Text)
+	})
+}
+
+// Examples returns up to n example sentences for a card: its pinned
+// sentences first, then sentences from index whose other words are in known,
+// the terms from KnownTerms.
+func Examples(ctx context.Context, dbc *DatabaseConn, term string, index *sentences.Index, known map[string]bool, n int) ([]sentences.Sentence, error) {
+	ids, err := dbc.findTerm(ctx, term)
+	if err != nil {
+		return nil, err
+	}
+	examples, err := dbc.pinnedSentences(ctx, ids[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(examples) >= n {
+		return examples[:n], nil
+	}
+
+	pinned := make(map[string]bool, len(examples))
+	for _, sentence := range examples {
+		pinned[sentence.Text] = true
+	}
+	for _, sentence := range index.Examples(term, n, known) {
+		if len(examples) == n {
+			break
+		}
+		if !pinned[sentence.Text] {
+			examples = append(examples, sentence)
+		}
+	}
+	return examples, nil
+}
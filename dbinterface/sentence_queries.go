@@ -0,0 +1,38 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flashcards/sentences"
+)
+
+func (dbc *DatabaseConn) pinSentence(ctx context.Context, termId int64, sentence sentences.Sentence) error {
+	exec := fmt.Sprintf("INSERT INTO %s (term_id, sentence, translation) VALUES (?, ?, ?)", dbc.table("sentences"))
+	if _, err := dbc.db.ExecContext(ctx, exec, termId, sentence.Text, sentence.Translation); err != nil {
+		return fmt.Errorf("pinSentence: %w", err)
+	}
+	return nil
+}
+
+func (dbc *DatabaseConn) pinnedSentences(ctx context.Context, termId int64) ([]sentences.Sentence, error) {
+	query := fmt.Sprintf("SELECT sentence, translation FROM %s WHERE term_id = ? ORDER BY id", dbc.table("sentences"))
+	rows, err := dbc.db.QueryContext(ctx, query, termId)
+	if err != nil {
+		return nil, fmt.Errorf("pinnedSentences: %w", err)
+	}
+	defer rows.Close()
+
+	var pinned []sentences.Sentence
+	for rows.Next() {
+		var sentence sentences.Sentence
+		if err := rows.Scan(&sentence.Text, &sentence.Translation); err != nil {
+			return nil, fmt.Errorf("pinnedSentences: %w", err)
+		}
+		pinned = append(pinned, sentence)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("pinnedSentences: %w", err)
+	}
+	return pinned, nil
+}
@@ -0,0 +1,84 @@
+package dbinterface
+
+import (
+	"context"
+	"time"
+)
+
+// TrashedTerm is a deleted term waiting in the trash.
+type TrashedTerm struct {
+	Term       string
+	Definition string
+	DeletedAt  time.Time
+}
+
+// Trash lists the deleted terms that can still be restored.
+func Trash(ctx context.Context, dbc *DatabaseConn) (map[int64]TrashedTerm, error) {
+	return dbc.listTrash(ctx)
+}
+
+// Restore takes a term out of the trash. It keeps its id, so its decks, tags,
+// sentences and review history come back with it. Undo puts it back in the
+// trash.
+func Restore(ctx context.Context, dbc *DatabaseConn, term string) error {
+	if err := verifyLanguage(dbc.language, term); err != nil {
+		return err
+	}
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		ids, err := tx.findTrashed(ctx, term)
+		if err != nil {
+			return err
+		}
+		if err := tx.restoreTerms(ctx, ids); err != nil {
+			return err
+		}
+		if err := tx.addHistory(ctx, ids, HistoryRestored, "from the trash"); err != nil {
+			return err
+		}
+		return tx.addJournalEntry(ctx, JournalEntry{Op: JournalRestore, Terms: []string{term}, Ids: ids})
+	})
+}
+
+// Purge deletes a term in the trash for good. It cannot be undone, and the
+// operations on the term drop out of the journal, as do the operations that
+// were undone, since redoing them could depend on the term.
+func Purge(ctx context.Context, dbc *DatabaseConn, term string) error {
+	if err := verifyLanguage(dbc.language, term); err != nil {
+		return err
+	}
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		ids, err := tx.findTrashed(ctx, term)
+		if err != nil {
+			return err
+		}
+		if err := tx.addHistory(ctx, ids, HistoryPurged, ""); err != nil {
+			return err
+		}
+		if err := tx.forgetJournalEntries(ctx, ids); err != nil {
+			return err
+		}
+		return tx.purgeTerms(ctx, ids)
+	})
+}
+
+// EmptyTrash purges every term in the trash. Like Purge, it cannot be
+// undone.
+func EmptyTrash(ctx context.Context, dbc *DatabaseConn) error {
+	return dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		trash, err := tx.listTrash(ctx)
+		if err != nil {
+			return err
+		}
+		ids := make([]int64, 0, len(trash))
+		for id := range trash {
+			ids = append(ids, id)
+		}
+		if err := tx.addHistory(ctx, ids, HistoryPurged, ""); err != nil {
+			return err
+		}
+		if err := tx.forgetJournalEntries(ctx, ids); err != nil {
+			return err
+		}
+		return tx.purgeTerms(ctx, ids)
+	})
+}
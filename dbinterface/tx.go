@@ -0,0 +1,59 @@
+package dbinterface
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// querier is what the queries need from a connection, so that they run the
+// same on the database and inside a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// txAttempts is how many times a transaction is tried when MySQL aborts it
+// because of a deadlock with another client.
+const txAttempts = 3
+
+// inTx runs fn with a connection whose queries all belong to one
+// transaction, committing if fn succeeds and rolling back otherwise. Calls
+// nested in fn join the outer transaction.
+func (dbc *DatabaseConn) inTx(ctx context.Context, fn func(tx *DatabaseConn) error) error {
+	if dbc.conn == nil {
+		return fn(dbc)
+	}
+	var err error
+	for attempt := 0; attempt < txAttempts; attempt++ {
+		if err = dbc.tryTx(ctx, fn); !isDeadlock(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func (dbc *DatabaseConn) tryTx(ctx context.Context, fn func(tx *DatabaseConn) error) error {
+	tx, err := dbc.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	txConn := *dbc
+	txConn.db = tx
+	txConn.conn = nil
+	if err := fn(&txConn); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// isDeadlock reports whether err is MySQL giving up on a transaction that
+// deadlocked or timed out waiting for a lock, which is worth retrying.
+func isDeadlock(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && (mysqlErr.Number == 1213 || mysqlErr.Number == 1205)
+}
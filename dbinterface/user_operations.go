@@ -0,0 +1,118 @@
+package dbinterface
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/flashcards/dict"
+	"github.com/flashcards/language"
+)
+
+// DefaultUser owns the cards of databases created before there were users.
+// Connect returns a connection for it.
+const DefaultUser = "default"
+
+// LibraryCard is a card another user shares.
+type LibraryCard struct {
+	Term       string
+	Definition string
+	Owner      string
+}
+
+// CreateUser adds a user with no cards and returns its id.
+func CreateUser(ctx context.Context, dbc *DatabaseConn, name string) (int64, error) {
+	if name == "" {
+		return 0, fmt.Errorf("CreateUser: missing name")
+	}
+	return dbc.createUser(ctx, name)
+}
+
+// ForUser returns a connection sharing dbc's database connections that
+// works on the cards, decks, tags, reviews and history of the user called
+// name.
+func ForUser(ctx context.Context, dbc *DatabaseConn, name string) (*DatabaseConn, error) {
+	id, err := dbc.findUser(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return dbc.asUser(id), nil
+}
+
+func (dbc *DatabaseConn) asUser(id int64) *DatabaseConn {
+	userConn := *dbc
+	userConn.userId = id
+	return &userConn
+}
+
+// inLanguage returns a copy of dbc working on the cards of lang.
+func (dbc *DatabaseConn) inLanguage(lang language.Language) *DatabaseConn {
+	langConn := *dbc
+	langConn.language = lang
+	return &langConn
+}
+
+// Users returns the names of all users keyed by id.
+func Users(ctx context.Context, dbc *DatabaseConn) (map[int64]string, error) {
+	return dbc.listUsers(ctx)
+}
+
+// Share puts a card in the library shared with other users, or takes it out
+// if shared is false. Other users can copy it but not change it.
+func Share(ctx context.Context, dbc *DatabaseConn, term string, shared bool) error {
+	if err := verifyLanguage(dbc.language, term); err != nil {
+		return err
+	}
+	num, err := dbc.shareTerm(ctx, term, shared)
+	if err != nil {
+		return err
+	}
+	if num == 0 {
+		if _, err := dbc.findTerm(ctx, term); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Library returns the cards other users share, keyed by id.
+func Library(ctx context.Context, dbc *DatabaseConn) (map[int64]LibraryCard, error) {
+	return dbc.listLibrary(ctx, 0)
+}
+
+// AddFromLibrary adds a shared card to the user's own cards the way Add
+// does, with the definition of the shared card. A card the user already has
+// is left as it is. The import is journaled as one operation, so a single
+// undo reverses it.
+func AddFromLibrary(ctx context.Context, dbc *DatabaseConn, id int64, dictionary dict.Dictionary) ([]int64, error) {
+	var entry JournalEntry
+	err := dbc.inTx(ctx, func(tx *DatabaseConn) error {
+		library, err := tx.listLibrary(ctx, id)
+		if err != nil {
+			return err
+		}
+		card, ok := library[id]
+		if !ok {
+			return &ErrNotFound{term: fmt.Sprintf("shared card %d", id)}
+		}
+		if err := verifyLanguage(tx.language, card.Term); err != nil {
+			return err
+		}
+		if entry, err = tx.add(ctx, card.Term, dictionary); err != nil || len(entry.Ids) == 0 {
+			return err
+		}
+		// Only the term itself takes the shared definition, and only if it
+		// was added or restored just now rather than already a card.
+		if entry.Terms[len(entry.Terms)-1] == card.Term {
+			edit, err := tx.edit(ctx, card.Term, card.Definition)
+			if err != nil {
+				return err
+			}
+			entry.Op, entry.Old, entry.New = JournalImport, edit.Old, edit.New
+		}
+		return tx.addJournalEntry(ctx, entry)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entry.Ids, nil
+}
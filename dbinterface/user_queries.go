@@ -0,0 +1,101 @@
+package dbinterface
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func (dbc *DatabaseConn) createUser(ctx context.Context, name string) (int64, error) {
+	exec := fmt.Sprintf("INSERT INTO %s (name) VALUES (?)", dbc.table("users"))
+	result, err := dbc.db.ExecContext(ctx, exec, name)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return 0, &ErrUserExists{name: name}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("createUser %q: %w", name, err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("createUser %q: %w", name, err)
+	}
+	return id, nil
+}
+
+func (dbc *DatabaseConn) findUser(ctx context.Context, name string) (int64, error) {
+	var id int64
+	query := fmt.Sprintf("SELECT id FROM %s WHERE name = ?", dbc.table("users"))
+	err := dbc.db.QueryRowContext(ctx, query, name).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, &ErrNotFound{term: name}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("findUser %q: %w", name, err)
+	}
+	return id, nil
+}
+
+func (dbc *DatabaseConn) listUsers(ctx context.Context) (map[int64]string, error) {
+	rows, err := dbc.db.QueryContext(ctx, fmt.Sprintf("SELECT id, name FROM %s", dbc.table("users")))
+	if err != nil {
+		return nil, fmt.Errorf("listUsers: %w", err)
+	}
+	defer rows.Close()
+
+	users := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, fmt.Errorf("listUsers: %w", err)
+		}
+		users[id] = name
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listUsers: %w", err)
+	}
+	return users, nil
+}
+
+func (dbc *DatabaseConn) shareTerm(ctx context.Context, term string, shared bool) (int64, error) {
+	exec := fmt.Sprintf("UPDATE %s SET shared = ? WHERE user_id = ? AND language = ? AND term = ? AND deleted_at IS NULL", dbc.tableName)
+	result, err := dbc.db.ExecContext(ctx, exec, shared, dbc.userId, dbc.language.Name(), term)
+	if err != nil {
+		return 0, fmt.Errorf("shareTerm: %w", err)
+	}
+	num, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("shareTerm: %w", err)
+	}
+	return num, nil
+}
+
+// listLibrary returns the cards in the connection's language other users
+// share, or only the one with id if it is not 0.
+func (dbc *DatabaseConn) listLibrary(ctx context.Context, id int64) (map[int64]LibraryCard, error) {
+	query := fmt.Sprintf(`SELECT t.id, t.term, t.definition, u.name FROM %s t JOIN %s u ON u.id = t.user_id
+		WHERE t.shared AND t.deleted_at IS NULL AND t.user_id <> ? AND t.language = ? AND (? = 0 OR t.id = ?)`, dbc.tableName, dbc.table("users"))
+	rows, err := dbc.db.QueryContext(ctx, query, dbc.userId, dbc.language.Name(), id, id)
+	if err != nil {
+		return nil, fmt.Errorf("listLibrary: %w", err)
+	}
+	defer rows.Close()
+
+	library := make(map[int64]LibraryCard)
+	for rows.Next() {
+		var id int64
+		var card LibraryCard
+		if err := rows.Scan(&id, &card.Term, &card.Definition, &card.Owner); err != nil {
+			return nil, fmt.Errorf("listLibrary: %w", err)
+		}
+		library[id] = card
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listLibrary: %w", err)
+	}
+	return library, nil
+}
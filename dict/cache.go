@@ -0,0 +1,206 @@
+package dict
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"log"
+	"os"
+)
+
+// cacheVersion is bumped whenever DictionaryEntry or the parsing rules change
+// so that caches written by older builds are rebuilt.
+const cacheVersion = 2
+
+var cacheMagic = []byte("FCDICT")
+
+var (
+	errStaleCache   = errors.New("dictionary cache is stale")
+	errCorruptCache = errors.New("dictionary cache is corrupt")
+)
+
+// The cache file is cacheMagic, the version and entry count as uvarints, the
+// SHA-256 of the source file, then every entry as four length-prefixed
+// strings followed by the number of glosses and the glosses. Loading reads the file in one go and slices all strings out of a
+// single buffer, which avoids an allocation per field.
+
+// minEntrySize is the size of an entry with empty strings and no glosses:
+// five zero uvarints of one byte each.
+const minEntrySize = 5
+
+func cachePath(filepath string) string {
+	return filepath + ".cache"
+}
+
+func checksum(filepath string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	file, err := os.Open(filepath)
+	if err != nil {
+		return sum, err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+type cacheReader struct {
+	raw  []byte
+	data string
+	pos  int
+	err  error
+}
+
+func (r *cacheReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.raw[r.pos:])
+	if n <= 0 {
+		r.err = errCorruptCache
+		return 0
+	}
+	r.pos += n
+	return v
+}
+
+func (r *cacheReader) string() string {
+	n := r.uvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > uint64(len(r.data)-r.pos) {
+		r.err = errCorruptCache
+		return ""
+	}
+	s := r.data[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return s
+}
+
+func readCache(filepath string, sum [sha256.Size]byte) (DictMap, error) {
+	data, err := os.ReadFile(cachePath(filepath))
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, cacheMagic) {
+		return nil, errCorruptCache
+	}
+
+	r := &cacheReader{raw: data, data: string(data), pos: len(cacheMagic)}
+	version := r.uvarint()
+	count := r.uvarint()
+	if r.err != nil {
+		return nil, r.err
+	}
+	if version != cacheVersion || len(r.data)-r.pos < len(sum) || r.data[r.pos:r.pos+len(sum)] != string(sum[:]) {
+		return nil, errStaleCache
+	}
+	r.pos += len(sum)
+	// Every entry takes at least minEntrySize bytes, so a count the rest of
+	// the file cannot hold is corrupt, and is not used to size the map.
+	if count > uint64(len(r.raw)-r.pos)/minEntrySize {
+		return nil, errCorruptCache
+	}
+
+	dictMap := make(DictMap, count)
+	for i := uint64(0); i < count; i++ {
+		entry := DictionaryEntry{
+			Traditional: r.string(),
+			Simplified:  r.string(),
+			Pinyin:      r.string(),
+			English:     r.string(),
+		}
+		if n := r.uvarint(); n > 0 && r.err == nil {
+			if n > uint64(len(r.raw)-r.pos) {
+				return nil, errCorruptCache
+			}
+			entry.Glosses = make([]string, n)
+			for j := range entry.Glosses {
+				entry.Glosses[j] = r.string()
+			}
+		}
+		if r.err != nil {
+			return nil, r.err
+		}
+		dictMap[entry.Simplified] = entry
+	}
+	return dictMap, nil
+}
+
+func writeCache(filepath string, sum [sha256.Size]byte, dictMap DictMap) error {
+	// Write to a temporary file first so a crash never leaves a truncated cache.
+	tmp, err := os.Create(cachePath(filepath) + ".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	buf := make([]byte, binary.MaxVarintLen64)
+	writeUvarint := func(v uint64) {
+		w.Write(buf[:binary.PutUvarint(buf, v)])
+	}
+	writeString := func(s string) {
+		writeUvarint(uint64(len(s)))
+		w.WriteString(s)
+	}
+
+	w.Write(cacheMagic)
+	writeUvarint(cacheVersion)
+	writeUvarint(uint64(len(dictMap)))
+	w.Write(sum[:])
+	for _, entry := range dictMap {
+		writeString(entry.Traditional)
+		writeString(entry.Simplified)
+		writeString(entry.Pinyin)
+		writeString(entry.English)
+		writeUvarint(uint64(len(entry.Glosses)))
+		for _, gloss := range entry.Glosses {
+			writeString(gloss)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), cachePath(filepath))
+}
+
+// LoadDict returns the dictionary at filepath, using the compiled cache
+// written next to it when the cache matches the file's checksum. Otherwise
+// the file is parsed and the cache rebuilt.
+func LoadDict(filepath string) (DictMap, error) {
+	sum, err := checksum(filepath)
+	if err != nil {
+		log.Println("Error opening file:", err)
+		return nil, err
+	}
+
+	dictMap, err := readCache(filepath, sum)
+	if err == nil {
+		return dictMap, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Ignoring dictionary cache: %v", err)
+	}
+
+	dictMap, err = ParseDict(filepath)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeCache(filepath, sum, dictMap); err != nil {
+		log.Printf("Unable to write dictionary cache: %v", err)
+	}
+	return dictMap, nil
+}
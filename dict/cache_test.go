@@ -0,0 +1,113 @@
+package dict
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTestDict writes a CC-CEDICT style file with n distinct entries.
+func writeTestDict(tb testing.TB, n int) string {
+	tb.Helper()
+	var b strings.Builder
+	b.WriteString("# CC-CEDICT\n")
+	for i := 0; i < n; i++ {
+		word := string(rune(0x4e00+i%20000)) + string(rune(0x4e00+i/20000))
+		fmt.Fprintf(&b, "%s %s [zi4 %d] /definition %d/second sense/\n", word, word, i%5+1, i)
+	}
+	path := filepath.Join(tb.TempDir(), "cedict_ts.u8")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDict(t *testing.T) {
+	path := writeTestDict(t, 100)
+
+	parsed, err := ParseDict(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := LoadDict(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(cachePath(path)); err != nil {
+		t.Fatalf("cache not written: %v", err)
+	}
+	cached, err := LoadDict(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed, first) || !reflect.DeepEqual(parsed, cached) {
+		t.Errorf("cached dictionary differs from parsed dictionary")
+	}
+
+	if err := os.WriteFile(path, []byte("好 好 [hao3] /good/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := LoadDict(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || changed["好"].English != "good" {
+		t.Errorf("stale cache used after source changed: got %d entries", len(changed))
+	}
+}
+
+// A cache claiming more entries than it holds is rebuilt without sizing
+// anything by the claimed count.
+func TestLoadDictCorruptCount(t *testing.T) {
+	path := writeTestDict(t, 10)
+	sum, err := checksum(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cache []byte
+	cache = append(cache, cacheMagic...)
+	cache = binary.AppendUvarint(cache, cacheVersion)
+	cache = binary.AppendUvarint(cache, 1<<62)
+	cache = append(cache, sum[:]...)
+	if err := os.WriteFile(cachePath(path), cache, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readCache(path, sum); !errors.Is(err, errCorruptCache) {
+		t.Errorf("Got error %v, wanted %v", err, errCorruptCache)
+	}
+	loaded, err := LoadDict(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 10 {
+		t.Errorf("Got %d entries, wanted the dictionary parsed again", len(loaded))
+	}
+}
+
+func BenchmarkParseDict(b *testing.B) {
+	path := writeTestDict(b, 120000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseDict(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkLoadDictCached(b *testing.B) {
+	path := writeTestDict(b, 120000)
+	if _, err := LoadDict(path); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := LoadDict(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
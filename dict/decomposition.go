@@ -0,0 +1,103 @@
+package dict
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Decomposition is the structure of a single character.
+type Decomposition struct {
+	Radical string
+	Strokes int
+	// IDS is the ideographic description sequence, e.g. ⿰女子.
+	IDS string
+	// Components are the character's components and, recursively, their
+	// components, in the order they appear.
+	Components []string
+}
+
+type hanziLine struct {
+	Character     string   `json:"character"`
+	Radical       string   `json:"radical"`
+	Decomposition string   `json:"decomposition"`
+	Strokes       []string `json:"strokes"`
+}
+
+func readHanziLines(filepath string, f func(hanziLine)) error {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var line hanziLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			return fmt.Errorf("%q: %v", filepath, err)
+		}
+		f(line)
+	}
+	return scanner.Err()
+}
+
+// idsComponents returns the components of an IDS, skipping the description
+// characters and the ？ used for unknown components.
+func idsComponents(ids string) []string {
+	var components []string
+	for _, r := range ids {
+		if (r >= '\u2ff0' && r <= '\u2fff') || r == '？' {
+			continue
+		}
+		components = append(components, string(r))
+	}
+	return components
+}
+
+// ParseMakeMeAHanzi reads the Make Me a Hanzi dictionary.txt for radicals and
+// decompositions, and graphics.txt for stroke counts. graphicsPath may be
+// empty or missing, leaving stroke counts at 0.
+func ParseMakeMeAHanzi(dictionaryPath, graphicsPath string) (map[string]Decomposition, error) {
+	decompositions := make(map[string]Decomposition)
+	err := readHanziLines(dictionaryPath, func(line hanziLine) {
+		decompositions[line.Character] = Decomposition{Radical: line.Radical, IDS: line.Decomposition}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ParseMakeMeAHanzi: %w", err)
+	}
+
+	if graphicsPath != "" {
+		err = readHanziLines(graphicsPath, func(line hanziLine) {
+			if d, ok := decompositions[line.Character]; ok {
+				d.Strokes = len(line.Strokes)
+				decompositions[line.Character] = d
+			}
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("ParseMakeMeAHanzi: %w", err)
+		}
+	}
+
+	for char, d := range decompositions {
+		d.Components = allComponents(decompositions, char, map[string]bool{char: true})
+		decompositions[char] = d
+	}
+	return decompositions, nil
+}
+
+func allComponents(decompositions map[string]Decomposition, char string, seen map[string]bool) []string {
+	var components []string
+	for _, c := range idsComponents(decompositions[char].IDS) {
+		if seen[c] {
+			continue
+		}
+		seen[c] = true
+		components = append(components, c)
+		components = append(components, allComponents(decompositions, c, seen)...)
+	}
+	return components
+}
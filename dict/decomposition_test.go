@@ -0,0 +1,37 @@
+package dict
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseMakeMeAHanzi(t *testing.T) {
+	dir := t.TempDir()
+	dictionary := filepath.Join(dir, "dictionary.txt")
+	graphics := filepath.Join(dir, "graphics.txt")
+	lines := `{"character":"妈","decomposition":"⿰女马","radical":"女"}
+{"character":"马","decomposition":"？","radical":"马"}
+{"character":"女","decomposition":"？","radical":"女"}
+{"character":"吗","decomposition":"⿰口马","radical":"口"}
+`
+	if err := os.WriteFile(dictionary, []byte(lines), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(graphics, []byte(`{"character":"妈","strokes":["M 1","M 2","M 3","M 4","M 5","M 6"],"medians":[]}`+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ParseMakeMeAHanzi(dictionary, graphics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Decomposition{Radical: "女", Strokes: 6, IDS: "⿰女马", Components: []string{"女", "马"}}
+	if !reflect.DeepEqual(got["妈"], want) {
+		t.Errorf("Got %+v; wanted %+v", got["妈"], want)
+	}
+	if len(got["马"].Components) != 0 {
+		t.Errorf("Got components %v for an undecomposed character", got["马"].Components)
+	}
+}
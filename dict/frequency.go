@@ -0,0 +1,69 @@
+package dict
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FrequencyRanks maps words to their frequency rank, 1 being the most
+// frequent.
+type FrequencyRanks map[string]int
+
+// ParseFrequency reads a SUBTLEX-CH style frequency list: tab separated
+// lines starting with a word and its count. Lines whose second column is not
+// a count, like the headers, are skipped.
+func ParseFrequency(filepath string) (FrequencyRanks, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	type wordCount struct {
+		word  string
+		count float64
+	}
+	var counts []wordCount
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+		if len(fields) < 2 || seen[fields[0]] {
+			continue
+		}
+		count, err := strconv.ParseFloat(strings.TrimSpace(fields[1]), 64)
+		if err != nil {
+			continue
+		}
+		seen[fields[0]] = true
+		counts = append(counts, wordCount{word: fields[0], count: count})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ParseFrequency %q: %v", filepath, err)
+	}
+
+	sort.SliceStable(counts, func(i, j int) bool {
+		return counts[i].count > counts[j].count
+	})
+	ranks := make(FrequencyRanks, len(counts))
+	for i, c := range counts {
+		ranks[c.word] = i + 1
+	}
+	return ranks, nil
+}
+
+// Words returns all words from most to least frequent.
+func (f FrequencyRanks) Words() []string {
+	words := make([]string, 0, len(f))
+	for word := range f {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		return f[words[i]] < f[words[j]]
+	})
+	return words
+}
@@ -0,0 +1,23 @@
+package dict
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFrequency(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "SUBTLEX-CH-WF.tsv")
+	data := "Total word count: 33546516\nWord\tWCount\tW/million\n的\t1690879\t50147.83\n我\t1450657\t43023.51\n你\t1643990\t48757.25\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ranks, err := ParseFrequency(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ranks.Words(); !reflect.DeepEqual(got, []string{"的", "你", "我"}) {
+		t.Errorf("Got %v; wanted [的 你 我]", got)
+	}
+}
@@ -0,0 +1,94 @@
+package dict
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HSKList is a local HSK word list. Each line holds a word, optionally
+// followed by a tab and its level. Lines without a level get Level.
+type HSKList struct {
+	Level int    `json:"level,omitempty"`
+	Path  string `json:"path"`
+}
+
+// DefaultHSKLists returns one list per level under dict/hsk/<version>/,
+// named HSK1.txt and so on. HSK 3.0 levels 7-9 share the HSK7.txt list.
+func DefaultHSKLists(version string) []HSKList {
+	levels := 6
+	if version == "3.0" {
+		levels = 7
+	}
+	var lists []HSKList
+	for level := 1; level <= levels; level++ {
+		lists = append(lists, HSKList{Level: level, Path: fmt.Sprintf("dict/hsk/%s/HSK%d.txt", version, level)})
+	}
+	return lists
+}
+
+// HSKLevels maps words to their HSK level.
+type HSKLevels map[string]int
+
+// LoadHSK reads the word lists. Missing files are skipped, and a word listed
+// at several levels keeps the lowest.
+func LoadHSK(lists []HSKList) (HSKLevels, error) {
+	levels := make(HSKLevels)
+	for _, list := range lists {
+		file, err := os.Open(list.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			log.Printf("HSK list %q not found, skipping", list.Path)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			fields := strings.Split(strings.TrimSpace(scanner.Text()), "\t")
+			word, level := fields[0], list.Level
+			if word == "" || word[0] == '#' {
+				continue
+			}
+			if len(fields) > 1 {
+				level, err = strconv.Atoi(strings.TrimSpace(fields[1]))
+				if err != nil {
+					file.Close()
+					return nil, fmt.Errorf("LoadHSK %q: bad level for %q: %v", list.Path, word, err)
+				}
+			}
+			if existing, ok := levels[word]; !ok || level < existing {
+				levels[word] = level
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("LoadHSK %q: %v", list.Path, err)
+		}
+	}
+	return levels, nil
+}
+
+// Words returns the words of a level in sorted order.
+func (h HSKLevels) Words(level int) []string {
+	var words []string
+	for word, l := range h {
+		if l == level {
+			words = append(words, word)
+		}
+	}
+	sort.Strings(words)
+	return words
+}
+
+// HSKTag is the tag given to cards of an HSK level.
+func HSKTag(level int) string {
+	return fmt.Sprintf("HSK%d", level)
+}
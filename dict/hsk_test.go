@@ -0,0 +1,36 @@
+package dict
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadHSK(t *testing.T) {
+	dir := t.TempDir()
+	level1 := filepath.Join(dir, "HSK1.txt")
+	mixed := filepath.Join(dir, "hsk.tsv")
+	if err := os.WriteFile(level1, []byte("爱\n八\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mixed, []byte("# word\tlevel\n爱\t2\n学习\t1\n办法\t3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	levels, err := LoadHSK([]HSKList{
+		{Level: 1, Path: level1},
+		{Path: mixed},
+		{Level: 2, Path: filepath.Join(dir, "missing.txt")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := HSKLevels{"爱": 1, "八": 1, "学习": 1, "办法": 3}
+	if !reflect.DeepEqual(levels, want) {
+		t.Errorf("Got %v; wanted %v", levels, want)
+	}
+	if got := levels.Words(1); !reflect.DeepEqual(got, []string{"八", "学习", "爱"}) {
+		t.Errorf("Got level 1 words %v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package dict
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+type jmdictEntry struct {
+	Kanji    []string `xml:"k_ele>keb"`
+	Readings []string `xml:"r_ele>reb"`
+	Senses   []struct {
+		Glosses []string `xml:"gloss"`
+	} `xml:"sense"`
+}
+
+// ParseJMdict parses a JMdict XML file. Entries are keyed by their first
+// kanji spelling, or by their reading for kana-only words, and the reading is
+// stored in the Pinyin field.
+func ParseJMdict(filepath string) (DictMap, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := xml.NewDecoder(file)
+	// JMdict declares its part-of-speech codes as DTD entities, which
+	// encoding/xml does not expand. Non-strict mode keeps them as text.
+	decoder.Strict = false
+
+	dictMap := make(DictMap)
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ParseJMdict %q: %v", filepath, err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "entry" {
+			continue
+		}
+
+		var e jmdictEntry
+		if err := decoder.DecodeElement(&e, &start); err != nil {
+			return nil, fmt.Errorf("ParseJMdict %q: %v", filepath, err)
+		}
+		if len(e.Readings) == 0 {
+			continue
+		}
+		var senses []string
+		for _, sense := range e.Senses {
+			senses = append(senses, strings.Join(sense.Glosses, ", "))
+		}
+		entry := DictionaryEntry{
+			Simplified: e.Readings[0],
+			Pinyin:     e.Readings[0],
+			English:    strings.Join(senses, "; "),
+		}
+		if len(e.Kanji) > 0 {
+			entry.Simplified = e.Kanji[0]
+		}
+		if _, exists := dictMap[entry.Simplified]; !exists {
+			dictMap[entry.Simplified] = entry
+		}
+	}
+	return dictMap, nil
+}
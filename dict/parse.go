@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"log"
 	"os"
-	"reflect"
 	"slices"
 	"strings"
 )
@@ -39,6 +38,7 @@ func parseLine(line string) DictionaryEntry {
 		Simplified:  simplified,
 		Pinyin:      pinyin,
 		English:     english,
+		Glosses:     parts[1:],
 	}
 }
 
@@ -65,7 +65,7 @@ func ParseDict(filepath string) (DictMap, error) {
 	log.Println("Parsing dictionary")
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
-		if entry := parseLine(scanner.Text()); !reflect.DeepEqual(entry, DictionaryEntry{}) {
+		if entry := parseLine(scanner.Text()); entry.Simplified != "" {
 			listOfEntries = append(listOfEntries, entry)
 		}
 	}
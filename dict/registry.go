@@ -0,0 +1,201 @@
+package dict
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	FormatCEDICT = "cedict"
+	FormatTSV    = "tsv"
+	FormatJMdict = "jmdict"
+)
+
+// UserSourceName is the provenance given to entries from the user dictionary.
+const UserSourceName = "user"
+
+// Source is a dictionary file to load into a Registry.
+type Source struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Format string `json:"format"`
+}
+
+// Dictionary looks up dictionary entries for a term.
+type Dictionary interface {
+	Lookup(term string) (DictionaryEntry, bool)
+	GetDefinition(term string) (string, bool)
+	GetPinyin(term string) (string, bool)
+}
+
+type loadedSource struct {
+	name    string
+	entries DictMap
+}
+
+// Registry combines several dictionaries. Sources are consulted in priority
+// order and the user dictionary comes before all of them, so a user's own
+// entries override the defaults. It is safe for concurrent use.
+type Registry struct {
+	userPath string
+	// mu guards the user dictionary and the annotations.
+	mu sync.RWMutex
+	// sources are in the order Lookup consults them, the user dictionary
+	// first.
+	sources []loadedSource
+	hsk     HSKLevels
+	ranks   FrequencyRanks
+	hanzi   map[string]Decomposition
+}
+
+func loadSource(source Source) (DictMap, error) {
+	switch source.Format {
+	case FormatCEDICT, "":
+		return LoadDict(source.Path)
+	case FormatTSV:
+		return ParseTSV(source.Path)
+	case FormatJMdict:
+		return ParseJMdict(source.Path)
+	}
+	return nil, fmt.Errorf("dictionary %q: unknown format %q", source.Name, source.Format)
+}
+
+// NewRegistry loads sources, highest priority first, and the user dictionary
+// at userPath, which does not have to exist yet.
+func NewRegistry(sources []Source, userPath string) (*Registry, error) {
+	user := loadedSource{name: UserSourceName, entries: make(DictMap)}
+	if userPath != "" {
+		entries, err := ParseTSV(userPath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("user dictionary: %w", err)
+		}
+		if entries != nil {
+			user.entries = entries
+		}
+	}
+
+	r := &Registry{userPath: userPath, sources: []loadedSource{user}}
+	for _, source := range sources {
+		entries, err := loadSource(source)
+		if err != nil {
+			return nil, fmt.Errorf("dictionary %q: %w", source.Name, err)
+		}
+		log.Printf("Loaded %d entries from dictionary %q", len(entries), source.Name)
+		r.sources = append(r.sources, loadedSource{name: source.Name, entries: entries})
+	}
+	return r, nil
+}
+
+// Lookup returns the entry from the highest priority dictionary containing
+// term, with the senses from every dictionary that has it.
+func (r *Registry) Lookup(term string) (DictionaryEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var merged DictionaryEntry
+	found := false
+	for _, source := range r.sources {
+		entry, ok := source.entries[term]
+		if !ok {
+			continue
+		}
+		if !found {
+			merged = entry
+			merged.Senses = nil
+			found = true
+		}
+		if merged.Traditional == "" {
+			merged.Traditional = entry.Traditional
+		}
+		if merged.Pinyin == "" {
+			merged.Pinyin = entry.Pinyin
+		}
+		if merged.English == "" {
+			merged.English = entry.English
+		}
+		if len(merged.Senses) > 0 {
+			merged.Glosses = append(merged.Glosses, entry.Glosses...)
+		}
+		merged.Senses = append(merged.Senses, Sense{
+			Source:  source.name,
+			Pinyin:  entry.Pinyin,
+			English: entry.English,
+		})
+	}
+	if found {
+		merged.HSKLevel = r.hsk[term]
+		merged.Rank = r.ranks[term]
+		if d, ok := r.hanzi[term]; ok {
+			merged.Decomposition = &d
+		}
+	}
+	return merged, found
+}
+
+// AnnotateFrequency makes Lookup report the frequency rank of terms.
+func (r *Registry) AnnotateFrequency(ranks FrequencyRanks) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.ranks = ranks
+}
+
+// AnnotateDecompositions makes Lookup report the structure of characters.
+func (r *Registry) AnnotateDecompositions(decompositions map[string]Decomposition) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hanzi = decompositions
+}
+
+// AnnotateHSK makes Lookup report the HSK level of terms in levels.
+func (r *Registry) AnnotateHSK(levels HSKLevels) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hsk = levels
+}
+
+func (r *Registry) GetDefinition(term string) (string, bool) {
+	entry, ok := r.Lookup(term)
+	if !ok || entry.English == "" {
+		return "", false
+	}
+	return entry.English, true
+}
+
+func (r *Registry) GetPinyin(term string) (string, bool) {
+	entry, ok := r.Lookup(term)
+	if !ok || entry.Pinyin == "" {
+		return "", false
+	}
+	return entry.Pinyin, true
+}
+
+// AddUserEntry adds entry to the user dictionary and saves it to disk.
+func (r *Registry) AddUserEntry(entry DictionaryEntry) error {
+	if entry.Simplified == "" {
+		return errors.New("AddUserEntry: missing term")
+	}
+	for _, field := range []string{entry.Simplified, entry.Traditional, entry.Pinyin, entry.English} {
+		if strings.ContainsAny(field, "\t\r\n") {
+			return errors.New("AddUserEntry: entry must not contain tabs or line breaks")
+		}
+	}
+	if r.userPath == "" {
+		return errors.New("AddUserEntry: no user dictionary configured")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	file, err := os.OpenFile(r.userPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := fmt.Fprintln(file, formatTSV(entry)); err != nil {
+		return err
+	}
+	r.sources[0].entries[entry.Simplified] = entry
+	return nil
+}
@@ -0,0 +1,113 @@
+package dict
+
+import (
+	"os"
+	"path/filepath"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestRegistry(t *testing.T) {
+	dir := t.TempDir()
+	cedict := filepath.Join(dir, "cedict_ts.u8")
+	glossary := filepath.Join(dir, "glossary.tsv")
+	userPath := filepath.Join(dir, "user_dict.tsv")
+	if err := os.WriteFile(cedict, []byte("學習 学习 [xue2 xi2] /to learn/\n接口 接口 [jie1 kou3] /interface/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(glossary, []byte("# team glossary\n接口\t\t\tAPI (in our codebase)\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := []Source{
+		{Name: "glossary", Path: glossary, Format: FormatTSV},
+		{Name: "CC-CEDICT", Path: cedict, Format: FormatCEDICT},
+	}
+	r, err := NewRegistry(sources, userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, _ := r.Lookup("接口")
+	want := DictionaryEntry{
+		Traditional: "接口",
+		Simplified:  "接口",
+		Pinyin:      "jie1 kou3",
+		English:     "API (in our codebase)",
+		Glosses:     []string{"interface"},
+		Senses: []Sense{
+			{Source: "glossary", English: "API (in our codebase)"},
+			{Source: "CC-CEDICT", Pinyin: "jie1 kou3", English: "interface"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %+v; wanted %+v", got, want)
+	}
+
+	if err := r.AddUserEntry(DictionaryEntry{Simplified: "学习", Pinyin: "xue2 xi2", English: "to study"}); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewRegistry(sources, userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if def, _ := reloaded.GetDefinition("学习"); def != "to study" {
+		t.Errorf("Got definition %q; wanted the user entry", def)
+	}
+	if _, ok := reloaded.GetDefinition("你好"); ok {
+		t.Errorf("Found a term that is in no dictionary")
+	}
+}
+
+func TestAddUserEntryRejectsSeparators(t *testing.T) {
+	userPath := filepath.Join(t.TempDir(), "user_dict.tsv")
+	r, err := NewRegistry(nil, userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]DictionaryEntry{
+		"tab in term":              {Simplified: "学\t习", English: "to study"},
+		"tab in definition":        {Simplified: "学习", English: "to\tstudy"},
+		"line break in pinyin":     {Simplified: "学习", Pinyin: "xue2\nxi2", English: "to study"},
+		"line break in definition": {Simplified: "学习", English: "to study\r\n"},
+	}
+	for name, entry := range tests {
+		t.Run(name, func(t *testing.T) {
+			if err := r.AddUserEntry(entry); err == nil {
+				t.Errorf("Got no error for %q", formatTSV(entry))
+			}
+		})
+	}
+	if _, err := os.Stat(userPath); !os.IsNotExist(err) {
+		t.Errorf("Got %v; wanted no user dictionary written", err)
+	}
+}
+
+// TestRegistryConcurrent is meant to be run with -race. Lookups may run
+// while the user adds entries, as they do when the API is served.
+func TestRegistryConcurrent(t *testing.T) {
+	r, err := NewRegistry(nil, filepath.Join(t.TempDir(), "user_dict.tsv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			term := fmt.Sprintf("词%d", i)
+			if err := r.AddUserEntry(DictionaryEntry{Simplified: term, English: "word"}); err != nil {
+				t.Error(err)
+			}
+			r.Lookup(term)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < 8; i++ {
+		if _, ok := r.Lookup(fmt.Sprintf("词%d", i)); !ok {
+			t.Errorf("Missing entry %d", i)
+		}
+	}
+}
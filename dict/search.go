@@ -3,16 +3,52 @@ package dict
 type DictionaryEntry struct {
 	Traditional string
 	Simplified  string
-	Pinyin      string
-	English     string
+	// Pinyin is the reading of the term, which is kana for Japanese.
+	Pinyin  string
+	English string
+	// Glosses lists every definition a CC-CEDICT entry gives, English being
+	// the first. A Registry collects them from all its CC-CEDICT sources.
+	Glosses []string
+	// HSKLevel is the term's HSK level, or 0 if it is not an HSK word or no
+	// HSK lists are loaded.
+	HSKLevel int
+	// Rank is the term's frequency rank, 1 being the most frequent, or 0 if
+	// it is not in the loaded frequency list.
+	Rank int
+	// Decomposition is set for single characters when character data is
+	// loaded.
+	Decomposition *Decomposition
+	// Senses lists every dictionary's reading of the term when the entry
+	// comes from a Registry, highest priority first.
+	Senses []Sense
+}
+
+// Sense is one dictionary's pinyin and definition for a term.
+type Sense struct {
+	Source  string
+	Pinyin  string
+	English string
 }
 
 type DictMap map[string]DictionaryEntry
 
-func (d *DictMap) GetDefinition(term string) (string, bool) {
-	entry, ok := (*d)[term]
+func (d DictMap) Lookup(term string) (DictionaryEntry, bool) {
+	entry, ok := d[term]
+	return entry, ok
+}
+
+func (d DictMap) GetDefinition(term string) (string, bool) {
+	entry, ok := d[term]
 	if !ok {
 		return "", false
 	}
 	return entry.English, ok
 }
+
+func (d DictMap) GetPinyin(term string) (string, bool) {
+	entry, ok := d[term]
+	if !ok {
+		return "", false
+	}
+	return entry.Pinyin, ok
+}
@@ -0,0 +1,48 @@
+package dict
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ParseTSV parses a glossary or wordlist with one entry per line:
+//
+//	simplified<TAB>traditional<TAB>pinyin<TAB>english
+//
+// Trailing columns may be left out, so a plain wordlist with one word per
+// line is also accepted. Lines starting with # are comments.
+func ParseTSV(filepath string) (DictMap, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	dictMap := make(DictMap)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || line[0] == '#' {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		fields = append(fields, make([]string, 4)...)
+		entry := DictionaryEntry{
+			Simplified:  strings.TrimSpace(fields[0]),
+			Traditional: strings.TrimSpace(fields[1]),
+			Pinyin:      strings.TrimSpace(fields[2]),
+			English:     strings.TrimSpace(fields[3]),
+		}
+		dictMap[entry.Simplified] = entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("ParseTSV %q: %v", filepath, err)
+	}
+	return dictMap, nil
+}
+
+func formatTSV(entry DictionaryEntry) string {
+	return strings.Join([]string{entry.Simplified, entry.Traditional, entry.Pinyin, entry.English}, "\t")
+}
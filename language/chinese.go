@@ -0,0 +1,38 @@
+package language
+
+import (
+	"unicode"
+
+	"github.com/flashcards/dict"
+	"github.com/flashcards/pinyin"
+)
+
+type Chinese struct{}
+
+func (Chinese) Name() string {
+	return "Chinese"
+}
+
+func (Chinese) InScript(r rune) bool {
+	return unicode.Is(unicode.Han, r)
+}
+
+// Tokenize returns every character of term, since each character of a word
+// is studied on its own as well.
+func (Chinese) Tokenize(term string) []string {
+	var tokens []string
+	for _, c := range term {
+		tokens = append(tokens, string(c))
+	}
+	return tokens
+}
+
+func (Chinese) FormatReading(reading string, style pinyin.Style) string {
+	return pinyin.Format(reading, style)
+}
+
+func (Chinese) Dictionaries() []dict.Source {
+	return []dict.Source{
+		{Name: "CC-CEDICT", Path: "dict/cedict_ts.u8", Format: dict.FormatCEDICT},
+	}
+}
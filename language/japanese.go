@@ -0,0 +1,41 @@
+package language
+
+import (
+	"fmt"
+	"unicode"
+
+	"github.com/flashcards/dict"
+	"github.com/flashcards/pinyin"
+)
+
+type Japanese struct{}
+
+func (Japanese) Name() string {
+	return "Japanese"
+}
+
+func (Japanese) InScript(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || r == 'ー' || r == '々'
+}
+
+// Tokenize returns the kanji of term. Kana are not studied on their own.
+func (Japanese) Tokenize(term string) []string {
+	var tokens []string
+	for _, c := range term {
+		if unicode.Is(unicode.Han, c) {
+			tokens = append(tokens, string(c))
+		}
+	}
+	return tokens
+}
+
+// FormatReading shows the kana reading followed by its romaji.
+func (Japanese) FormatReading(reading string, _ pinyin.Style) string {
+	return fmt.Sprintf("%s %s", reading, Romaji(reading))
+}
+
+func (Japanese) Dictionaries() []dict.Source {
+	return []dict.Source{
+		{Name: "JMdict", Path: "dict/JMdict_e.xml", Format: dict.FormatJMdict},
+	}
+}
@@ -0,0 +1,46 @@
+package language
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flashcards/dict"
+	"github.com/flashcards/pinyin"
+)
+
+// Language describes a language cards can be made for.
+type Language interface {
+	Name() string
+	// InScript reports whether r belongs to the language's writing system.
+	InScript(r rune) bool
+	// Tokenize splits a term into the smaller units that also get their own
+	// card, such as the characters of a Chinese word.
+	Tokenize(term string) []string
+	// FormatReading renders a dictionary reading for display. Languages
+	// without pinyin ignore style.
+	FormatReading(reading string, style pinyin.Style) string
+	// Dictionaries are the default dictionary sources for the language.
+	Dictionaries() []dict.Source
+}
+
+var languages = []Language{Chinese{}, Japanese{}}
+
+// ByName returns the language with the given name, ignoring case.
+func ByName(name string) (Language, error) {
+	for _, lang := range languages {
+		if strings.EqualFold(lang.Name(), name) {
+			return lang, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported language %q", name)
+}
+
+// Validate returns the first rune of term that is not in lang's script.
+func Validate(lang Language, term string) (rune, bool) {
+	for _, c := range term {
+		if !lang.InScript(c) {
+			return c, false
+		}
+	}
+	return 0, true
+}
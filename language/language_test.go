@@ -0,0 +1,35 @@
+package language
+
+import "testing"
+
+func TestRomaji(t *testing.T) {
+	tests := map[string]string{
+		"ひらがな":  "hiragana",
+		"きょう":   "kyou",
+		"しゃしん":  "shashin",
+		"がっこう":  "gakkou",
+		"まっちゃ":  "matcha",
+		"コーヒー":  "koohii",
+		"きんえん":  "kin'en",
+		"ジャーナル": "jaanaru",
+		"ちょっと":  "chotto",
+		"こんにちは": "konnichiha",
+	}
+	for in, want := range tests {
+		if got := Romaji(in); got != want {
+			t.Errorf("Romaji(%q) = %q; wanted %q", in, got, want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	if got := (Japanese{}).Tokenize("食べ物"); len(got) != 2 || got[0] != "食" || got[1] != "物" {
+		t.Errorf("Got %v; wanted [食 物]", got)
+	}
+	if _, ok := Validate(Chinese{}, "学习"); !ok {
+		t.Errorf("学习 should be valid Chinese")
+	}
+	if r, ok := Validate(Chinese{}, "食べ物"); ok || r != 'べ' {
+		t.Errorf("Got %q; wanted べ to be rejected", r)
+	}
+}
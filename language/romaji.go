@@ -0,0 +1,91 @@
+package language
+
+import "strings"
+
+var kanaRomaji = map[rune]string{
+	'あ': "a", 'い': "i", 'う': "u", 'え': "e", 'お': "o",
+	'か': "ka", 'き': "ki", 'く': "ku", 'け': "ke", 'こ': "ko",
+	'が': "ga", 'ぎ': "gi", 'ぐ': "gu", 'げ': "ge", 'ご': "go",
+	'さ': "sa", 'し': "shi", 'す': "su", 'せ': "se", 'そ': "so",
+	'ざ': "za", 'じ': "ji", 'ず': "zu", 'ぜ': "ze", 'ぞ': "zo",
+	'た': "ta", 'ち': "chi", 'つ': "tsu", 'て': "te", 'と': "to",
+	'だ': "da", 'ぢ': "ji", 'づ': "zu", 'で': "de", 'ど': "do",
+	'な': "na", 'に': "ni", 'ぬ': "nu", 'ね': "ne", 'の': "no",
+	'は': "ha", 'ひ': "hi", 'ふ': "fu", 'へ': "he", 'ほ': "ho",
+	'ば': "ba", 'び': "bi", 'ぶ': "bu", 'べ': "be", 'ぼ': "bo",
+	'ぱ': "pa", 'ぴ': "pi", 'ぷ': "pu", 'ぺ': "pe", 'ぽ': "po",
+	'ま': "ma", 'み': "mi", 'む': "mu", 'め': "me", 'も': "mo",
+	'や': "ya", 'ゆ': "yu", 'よ': "yo",
+	'ら': "ra", 'り': "ri", 'る': "ru", 'れ': "re", 'ろ': "ro",
+	'わ': "wa", 'ゐ': "i", 'ゑ': "e", 'を': "o", 'ん': "n", 'ゔ': "vu",
+	'ぁ': "a", 'ぃ': "i", 'ぅ': "u", 'ぇ': "e", 'ぉ': "o",
+}
+
+var smallY = map[rune]string{'ゃ': "a", 'ゅ': "u", 'ょ': "o"}
+
+// toHiragana maps katakana to the matching hiragana.
+func toHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - ('ァ' - 'ぁ')
+	}
+	return r
+}
+
+// Romaji converts kana to Hepburn romaji. Runes that are not kana are kept.
+func Romaji(kana string) string {
+	runes := []rune(kana)
+	for i, r := range runes {
+		runes[i] = toHiragana(r)
+	}
+
+	var syllables []string
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		s, ok := kanaRomaji[r]
+		switch {
+		case ok && i+1 < len(runes) && smallY[runes[i+1]] != "" && strings.HasSuffix(s, "i") && len(s) > 1:
+			stem := strings.TrimSuffix(s, "i")
+			if !strings.HasSuffix(stem, "sh") && !strings.HasSuffix(stem, "ch") && stem != "j" {
+				stem += "y"
+			}
+			s = stem + smallY[runes[i+1]]
+			i++
+		case ok:
+		case r == 'ゃ' || r == 'ゅ' || r == 'ょ':
+			s = "y" + smallY[r]
+		case r == 'っ':
+			s = "っ"
+		case r == 'ー':
+			s = "ー"
+		default:
+			s = string(r)
+		}
+		syllables = append(syllables, s)
+	}
+
+	var b strings.Builder
+	for i, s := range syllables {
+		next := ""
+		if i+1 < len(syllables) {
+			next = syllables[i+1]
+		}
+		switch {
+		case s == "っ":
+			if strings.HasPrefix(next, "ch") {
+				b.WriteString("t")
+			} else if next != "" && !strings.ContainsAny(next[:1], "aiueo") {
+				b.WriteString(next[:1])
+			}
+		case s == "ー":
+			if i > 0 {
+				prev := syllables[i-1]
+				b.WriteString(prev[len(prev)-1:])
+			}
+		case s == "n" && next != "" && strings.ContainsAny(next[:1], "aiueoy"):
+			b.WriteString("n'")
+		default:
+			b.WriteString(s)
+		}
+	}
+	return b.String()
+}
@@ -1,17 +1,48 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
+	"net/http"
 	"os"
-	"sort"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
 
+	"github.com/flashcards/api"
+	"github.com/flashcards/backup"
+	"github.com/flashcards/config"
 	"github.com/flashcards/dbinterface"
 	"github.com/flashcards/dict"
+	"github.com/flashcards/language"
+	"github.com/flashcards/pinyin"
+	"github.com/flashcards/quiz"
+	"github.com/flashcards/sentences"
+	"github.com/flashcards/strokes"
 	"github.com/go-sql-driver/mysql"
 )
 
-func add(dbc *dbinterface.DatabaseConn, dictMap dict.DictMap) {
+// opTimeout is how long the database may take over one operation of the
+// menu. It is set from the config when the app starts.
+var opTimeout = 30 * time.Second
+
+// operation returns the context for one operation, which is done after
+// opTimeout. Menus create one per operation rather than one for the whole
+// menu, so time spent waiting for input does not count.
+func operation(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, opTimeout)
+}
+
+func add(ctx context.Context, dbc *dbinterface.DatabaseConn, view display) {
 	fmt.Println("Enter the term(s) you want to add to the database. Type menu to return to menu.")
 	for {
 		var input string
@@ -22,18 +53,26 @@ func add(dbc *dbinterface.DatabaseConn, dictMap dict.DictMap) {
 		if input == "menu" {
 			return
 		}
-		ids, err := dbinterface.Add(dbc, input, dictMap)
+		ctx, cancel := operation(ctx)
+		ids, err := dbinterface.Add(ctx, dbc, input, view.dictionary)
 		if err != nil {
 			log.Printf("Add error: %v", err)
 		}
 		if len(ids) > 0 {
 			fmt.Printf("Added IDs: %v\n", ids)
+			known, err := dbinterface.KnownTerms(ctx, dbc)
+			if err != nil {
+				log.Printf("Examples error: %v", err)
+			} else {
+				printExamples(ctx, dbc, input, known, view)
+			}
 		}
+		cancel()
 	}
 }
 
-func delete(dbc *dbinterface.DatabaseConn) {
-	fmt.Println("Enter the term(s) you want to delete from the database. Type menu to return to menu.")
+func delete(ctx context.Context, dbc *dbinterface.DatabaseConn) {
+	fmt.Println("Enter the term(s) you want to move to the trash. Type menu to return to menu.")
 	for {
 		var input string
 		_, err := fmt.Scan(&input)
@@ -43,26 +82,53 @@ func delete(dbc *dbinterface.DatabaseConn) {
 		if input == "menu" {
 			return
 		}
-		err = dbinterface.Delete(dbc, input)
+		ctx, cancel := operation(ctx)
+		err = dbinterface.Delete(ctx, dbc, input)
+		cancel()
 		if err != nil {
 			log.Printf("Delete error: %v", err)
 		}
 	}
 }
 
-func printTerms(terms map[int64]dbinterface.TermDef) {
-	var ids []int
-	for t := range terms {
-		ids = append(ids, int(t))
+// display is how terms are shown to the user.
+type display struct {
+	dictionary dict.Dictionary
+	lang       language.Language
+	style      pinyin.Style
+	order      dbinterface.Order
+	sentences  *sentences.Index
+	// examples is the number of example sentences shown with a card.
+	examples int
+}
+
+func newDisplay(dictionary dict.Dictionary, lang language.Language, index *sentences.Index, prefs config.Config) display {
+	order := dbinterface.ById
+	if prefs.SortByFrequency {
+		order = dbinterface.ByFrequency
+	}
+	return display{
+		dictionary: dictionary,
+		lang:       lang,
+		style:      prefs.PinyinStyle,
+		order:      order,
+		sentences:  index,
+		examples:   prefs.ExampleSentences,
 	}
-	sort.Ints(ids)
+}
 
-	for _, t := range ids {
-		fmt.Printf("%d: %v\n", t, terms[int64(t)])
+func printTerms(terms map[int64]dbinterface.TermDef, view display) {
+	for _, t := range dbinterface.SortIds(terms, view.order, view.dictionary) {
+		term := terms[t]
+		if p, ok := view.dictionary.GetPinyin(term.Term); ok {
+			fmt.Printf("%d: %s [%s] %s\n", t, term.Term, view.lang.FormatReading(p, view.style), term.Definition)
+		} else {
+			fmt.Printf("%d: %s %s\n", t, term.Term, term.Definition)
+		}
 	}
 }
 
-func find(dbc *dbinterface.DatabaseConn) {
+func find(ctx context.Context, dbc *dbinterface.DatabaseConn, view display) {
 	fmt.Println("Enter the term(s) you want to find in the database. Type menu to return to menu.")
 	for {
 		var input string
@@ -73,19 +139,53 @@ func find(dbc *dbinterface.DatabaseConn) {
 		if input == "menu" {
 			return
 		}
-		terms, err := dbinterface.Find(dbc, input)
+		ctx, cancel := operation(ctx)
+		terms, err := dbinterface.Find(ctx, dbc, input)
 		if err != nil {
 			log.Printf("Find error: %v", err)
 		}
 		if len(terms) == 0 {
 			fmt.Printf("no terms found with %s\n", input)
 		}
-		printTerms(terms)
+		printTerms(terms, view)
+		for _, termDef := range terms {
+			if termDef.Term == input && utf8.RuneCountInString(input) == 1 {
+				printStructure(ctx, dbc, input, view)
+			}
+		}
+		cancel()
 	}
 }
 
-func list(dbc *dbinterface.DatabaseConn) {
-	terms, err := dbinterface.List(dbc)
+// printStructure shows a character's structure and the other cards that
+// share its components.
+func printStructure(ctx context.Context, dbc *dbinterface.DatabaseConn, char string, view display) {
+	d, err := dbinterface.Structure(ctx, dbc, char)
+	if err != nil {
+		log.Printf("Structure error: %v", err)
+		return
+	}
+	if d == nil {
+		return
+	}
+	fmt.Printf("%s: radical %s, %d strokes, %s\n", char, d.Radical, d.Strokes, d.IDS)
+	sharing, err := dbinterface.SharingComponents(ctx, dbc, char)
+	if err != nil {
+		log.Printf("Structure error: %v", err)
+		return
+	}
+	for _, component := range d.Components {
+		if terms, ok := sharing[component]; ok {
+			fmt.Printf("Other cards with %s:\n", component)
+			printTerms(terms, view)
+		}
+	}
+}
+
+func list(ctx context.Context, dbc *dbinterface.DatabaseConn, view display) {
+	ctx, cancel := operation(ctx)
+	defer cancel()
+	terms, err := dbinterface.List(ctx, dbc)
 	if err != nil {
 		log.Printf("List error: %v", err)
 	}
@@ -93,10 +193,1165 @@ func list(dbc *dbinterface.DatabaseConn) {
 		fmt.Println("No terms in flashcards database.")
 		return
 	}
-	printTerms(terms)
+	known := make(map[string]bool, len(terms))
+	for _, termDef := range terms {
+		known[termDef.Term] = true
+	}
+	for _, id := range dbinterface.SortIds(terms, view.order, view.dictionary) {
+		printTerms(map[int64]dbinterface.TermDef{id: terms[id]}, view)
+		printExamples(ctx, dbc, terms[id].Term, known, view)
+	}
+}
+
+func printExamples(ctx context.Context, dbc *dbinterface.DatabaseConn, term string, known map[string]bool, view display) {
+	if view.examples == 0 {
+		return
+	}
+	examples, err := dbinterface.Examples(ctx, dbc, term, view.sentences, known, view.examples)
+	if err != nil {
+		log.Printf("Examples error: %v", err)
+		return
+	}
+	for _, example := range examples {
+		fmt.Printf("    %s %s\n", example.Text, example.Translation)
+	}
+}
+
+func exampleSentences(ctx context.Context, dbc *dbinterface.DatabaseConn, view display) {
+	fmt.Println("Enter the card to pick an example sentence for:")
+	var input string
+	_, err := fmt.Scan(&input)
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+	opCtx, cancel := operation(ctx)
+	known, err := dbinterface.KnownTerms(opCtx, dbc)
+	cancel()
+	if err != nil {
+		log.Printf("Examples error: %v", err)
+		return
+	}
+	examples := view.sentences.Examples(input, 10, known)
+	if len(examples) == 0 {
+		fmt.Printf("No example sentences with %s\n", input)
+		return
+	}
+	for i, example := range examples {
+		fmt.Printf("%d. %s %s\n", i+1, example.Text, example.Translation)
+	}
+	fmt.Println("Enter the number of the sentence to pin to the card:")
+	choice, ok := scanChoice(len(examples))
+	if !ok {
+		return
+	}
+	ctx, cancel = operation(ctx)
+	defer cancel()
+	if err := dbinterface.PinSentence(ctx, dbc, input, examples[choice-1]); err != nil {
+		log.Printf("Pin sentence error: %v", err)
+	}
+}
+
+func clozeCards(ctx context.Context, dbc *dbinterface.DatabaseConn, view display, opts quiz.Options) {
+	fmt.Println("1. Create a cloze card")
+	fmt.Println("2. Quiz cloze cards")
+	choice, ok := scanChoice(2)
+	if !ok {
+		return
+	}
+	if choice == 1 {
+		createCloze(ctx, dbc)
+		return
+	}
+	clozeQuiz(ctx, dbc, view, opts)
+}
+
+func createCloze(ctx context.Context, dbc *dbinterface.DatabaseConn) {
+	fmt.Println("Enter the card to make a cloze card for:")
+	var input string
+	_, err := fmt.Scan(&input)
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+	opCtx, cancel := operation(ctx)
+	pinned, err := dbinterface.PinnedSentences(opCtx, dbc, input)
+	cancel()
+	if err != nil {
+		log.Printf("Cloze error: %v", err)
+		return
+	}
+	fmt.Println("1. Enter a sentence")
+	for i, sentence := range pinned {
+		fmt.Printf("%d. %s %s\n", i+2, sentence.Text, sentence.Translation)
+	}
+	choice, ok := scanChoice(len(pinned) + 1)
+	if !ok {
+		return
+	}
+	var sentence sentences.Sentence
+	if choice == 1 {
+		fmt.Println("Enter the sentence:")
+		if sentence.Text, err = readLine(); err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		fmt.Println("Enter its translation, or - for none:")
+		if sentence.Translation, err = readLine(); err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		if sentence.Translation == "-" {
+			sentence.Translation = ""
+		}
+	} else {
+		sentence = pinned[choice-2]
+	}
+	ctx, cancel = operation(ctx)
+	defer cancel()
+	if _, err := dbinterface.AddCloze(ctx, dbc, input, sentence); err != nil {
+		log.Printf("Cloze error: %v", err)
+		return
+	}
+	fmt.Println(quiz.Blank(sentence.Text, input))
+}
+
+func clozeQuiz(ctx context.Context, dbc *dbinterface.DatabaseConn, view display, opts quiz.Options) {
+	opCtx, cancel := operation(ctx)
+	clozes, err := dbinterface.ListClozes(opCtx, dbc)
+	cancel()
+	if err != nil {
+		log.Printf("Cloze error: %v", err)
+		return
+	}
+	if len(clozes) == 0 {
+		fmt.Println("No cloze cards.")
+		return
+	}
+	ids := make([]int64, 0, len(clozes))
+	for id := range clozes {
+		ids = append(ids, id)
+	}
+	rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	fmt.Println("Fill in the blank with the word or its pinyin. Type menu to return to menu.")
+	correct := 0
+	for n, id := range ids {
+		cloze := clozes[id]
+		fmt.Println(quiz.Blank(cloze.Sentence, cloze.Term))
+		if cloze.Translation != "" {
+			fmt.Printf("    %s\n", cloze.Translation)
+		}
+		answer, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		if answer == "menu" {
+			fmt.Printf("%d of %d correct\n", correct, n)
+			return
+		}
+		entry, _ := view.dictionary.Lookup(cloze.Term)
+		reading := entry.Pinyin
+		// The translation gives the meaning away, so only the word and its
+		// reading count.
+		match := quiz.Grade(answer, cloze.Term, entry, opts)
+		ok := match == quiz.TermMatch || match == quiz.ReadingMatch
+		opCtx, cancel := operation(ctx)
+		if err := dbinterface.RecordClozeReview(opCtx, dbc, id, answer, ok); err != nil {
+			log.Printf("Cloze error: %v", err)
+		}
+		cancel()
+		if ok {
+			correct++
+			fmt.Println("Correct!")
+		} else {
+			fmt.Printf("The answer is %s %s\n", cloze.Term, view.lang.FormatReading(reading, view.style))
+		}
+	}
+	fmt.Printf("%d of %d correct\n", correct, len(ids))
+}
+
+func deckDirections(ctx context.Context, dbc *dbinterface.DatabaseConn, deckName string, names []string) error {
+	if len(names) > 0 {
+		directions := make([]dbinterface.Direction, len(names))
+		for i, name := range names {
+			var err error
+			if directions[i], err = dbinterface.ParseDirection(name); err != nil {
+				return err
+			}
+		}
+		if err := dbinterface.SetDeckDirections(ctx, dbc, deckName, directions); err != nil {
+			return err
+		}
+	}
+	directions, err := dbinterface.DeckDirections(ctx, dbc, deckName)
+	if err != nil {
+		return err
+	}
+	for _, direction := range directions {
+		fmt.Println(direction)
+	}
+	return nil
+}
+
+// cardSides returns what a card shows and the answer it asks for. ok is false
+// for pinyin directions of terms with no reading in the dictionary.
+func cardSides(card dbinterface.Card, view display) (prompt, answer string, ok bool) {
+	reading, _ := view.dictionary.GetPinyin(card.Term)
+	reading = view.lang.FormatReading(reading, view.style)
+	switch card.Direction {
+	case dbinterface.TermToDefinition:
+		return card.Term, card.Definition, true
+	case dbinterface.DefinitionToTerm:
+		return card.Definition, card.Term, true
+	case dbinterface.PinyinToHanzi:
+		return reading, card.Term, reading != ""
+	case dbinterface.HanziToPinyin:
+		return card.Term, reading, reading != ""
+	}
+	return "", "", false
+}
+
+// review shows the cards of a deck one side at a time and records how the
+// user grades themselves on each direction.
+func review(ctx context.Context, dbc *dbinterface.DatabaseConn, view display) {
+	fmt.Println("Enter the deck to review, or - for all cards:")
+	input, err := readLine()
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+	filter := dbinterface.Filter{Deck: input}
+	if input == "-" {
+		filter.Deck = ""
+	}
+	opCtx, cancel := operation(ctx)
+	cards, err := dbinterface.Cards(opCtx, dbc, filter)
+	cancel()
+	if err != nil {
+		log.Printf("Review error: %v", err)
+		return
+	}
+	rand.Shuffle(len(cards), func(i, j int) { cards[i], cards[j] = cards[j], cards[i] })
+
+	fmt.Println("Press enter to reveal the answer. Type menu to return to menu.")
+	for _, card := range cards {
+		prompt, answer, ok := cardSides(card, view)
+		if !ok {
+			continue
+		}
+		fmt.Printf("[%s] %s\n", card.Direction, prompt)
+		line, err := readRawLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		if strings.TrimSpace(line) == "menu" {
+			return
+		}
+		fmt.Println(answer)
+		fmt.Println("1. Got it right  2. Unsure  3. Got it wrong")
+		choice, ok := scanChoice(3)
+		if !ok {
+			return
+		}
+		// Skip the rest of the line so the next prompt waits for enter.
+		if _, err := readRawLine(); err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		grade := []dbinterface.Grade{dbinterface.Right, dbinterface.Unsure, dbinterface.Wrong}[choice-1]
+		opCtx, cancel := operation(ctx)
+		if err := dbinterface.RecordGrade(opCtx, dbc, card, grade); err != nil {
+			log.Printf("Review error: %v", err)
+		}
+		cancel()
+	}
+	fmt.Println("No more cards.")
+}
+
+// typedQuiz asks for each card that is not a leech in turn, alternating between showing the term
+// and showing its definition. The answer is graded against every other part
+// of the card, so a term prompt accepts the pinyin or an English keyword and
+// a definition prompt accepts the term or its pinyin.
+func typedQuiz(ctx context.Context, dbc *dbinterface.DatabaseConn, view display, opts quiz.Options) {
+	opCtx, cancel := operation(ctx)
+	terms, err := dbinterface.ListFiltered(opCtx, dbc, dbinterface.WithoutLeeches(dbinterface.Filter{}))
+	cancel()
+	if err != nil {
+		log.Printf("Quiz error: %v", err)
+		return
+	}
+	if len(terms) == 0 {
+		fmt.Println("No cards.")
+		return
+	}
+	ids := make([]int64, 0, len(terms))
+	for id := range terms {
+		ids = append(ids, id)
+	}
+	rand.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	fmt.Println("Type the answer. Type menu to return to menu.")
+	correct := 0
+	for n, id := range ids {
+		termDef := terms[id]
+		entry, _ := view.dictionary.Lookup(termDef.Term)
+		shown := quiz.TermMatch
+		if n%2 == 1 && termDef.Definition != "" {
+			shown = quiz.MeaningMatch
+			fmt.Println(termDef.Definition)
+		} else {
+			fmt.Println(termDef.Term)
+		}
+		answer, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		if answer == "menu" {
+			fmt.Printf("%d of %d correct\n", correct, n)
+			return
+		}
+		match := quiz.Grade(answer, termDef.Term, entry, opts)
+		ok := match != quiz.NoMatch && match != shown
+		opCtx, cancel := operation(ctx)
+		if err := dbinterface.RecordAnswer(opCtx, dbc, id, answer, ok); err != nil {
+			log.Printf("Quiz error: %v", err)
+		}
+		cancel()
+		if ok {
+			correct++
+			fmt.Println("Correct!")
+		} else {
+			printTerms(map[int64]dbinterface.TermDef{id: termDef}, view)
+		}
+	}
+	fmt.Printf("%d of %d correct\n", correct, len(ids))
+}
+
+// batch reads add, delete and edit lines and applies them all at once, or
+// none of them if one fails.
+func batch(ctx context.Context, dbc *dbinterface.DatabaseConn, dictionary dict.Dictionary) {
+	fmt.Println(`Enter one operation per line, then done to apply them all or menu to
+return to menu without applying anything. Quote definitions containing spaces.
+  add <term>
+  delete <term>
+  edit <term> <definition>`)
+	var ops []dbinterface.BatchOp
+	for {
+		line, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		args := append(splitArgs(line), "", "")
+		switch args[0] {
+		case "menu":
+			return
+		case "done":
+			ctx, cancel := operation(ctx)
+			results, err := dbinterface.Batch(ctx, dbc, ops, dictionary)
+			cancel()
+			for _, result := range results {
+				fmt.Printf("%s %s: %s", result.Op.Kind, result.Op.Term, result.Status)
+				if len(result.Ids) > 0 {
+					fmt.Printf(", IDs %v", result.Ids)
+				}
+				if result.Err != nil {
+					fmt.Printf(", %v", result.Err)
+				}
+				fmt.Println()
+			}
+			if err != nil {
+				log.Printf("Batch error: %v", err)
+			}
+			return
+		case "add", "delete", "edit":
+			ops = append(ops, dbinterface.BatchOp{Kind: dbinterface.BatchKind(args[0]), Term: args[1], Definition: args[2]})
+		default:
+			fmt.Printf("Unknown operation %q\n", args[0])
+		}
+	}
+}
+
+// undoRedo reverses or repeats the last adds, deletes and edits.
+func undoRedo(ctx context.Context, dbc *dbinterface.DatabaseConn) {
+	fmt.Println(`Enter a command. Type menu to return to menu.
+  undo [n]   undo the last n operations, 1 by default
+  redo [n]   redo the last n undone operations, 1 by default`)
+	for {
+		line, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		args := append(strings.Fields(line), "1")
+		if args[0] == "menu" {
+			return
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			fmt.Printf("Invalid count %q\n", args[1])
+			continue
+		}
+		var entries []dbinterface.JournalEntry
+		ctx, cancel := operation(ctx)
+		switch args[0] {
+		case "undo":
+			entries, err = dbinterface.Undo(ctx, dbc, n)
+		case "redo":
+			entries, err = dbinterface.Redo(ctx, dbc, n)
+		default:
+			cancel()
+			fmt.Printf("Unknown command %q\n", args[0])
+			continue
+		}
+		cancel()
+		if err != nil {
+			log.Printf("%s error: %v", args[0], err)
+			continue
+		}
+		if len(entries) == 0 {
+			fmt.Printf("Nothing to %s.\n", args[0])
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s: %s %s\n", args[0], entry.Op, strings.Join(entry.Terms, ", "))
+		}
+	}
+}
+
+// history prints the timeline of a card.
+func history(ctx context.Context, dbc *dbinterface.DatabaseConn) {
+	fmt.Println("Enter history <term> to see everything that happened to a card. Type menu to return to menu.")
+	for {
+		line, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		args := append(strings.Fields(line), "")
+		switch args[0] {
+		case "menu":
+			return
+		case "history":
+			ctx, cancel := operation(ctx)
+			events, err := dbinterface.History(ctx, dbc, args[1])
+			cancel()
+			if err != nil {
+				log.Printf("history error: %v", err)
+				continue
+			}
+			if len(events) == 0 {
+				fmt.Printf("No history for %q\n", args[1])
+			}
+			for _, event := range events {
+				fmt.Printf("%s  #%d %s", event.At.Format("2006-01-02 15:04:05"), event.TermId, event.Kind)
+				if event.Detail != "" {
+					fmt.Printf(": %s", event.Detail)
+				}
+				fmt.Println()
+			}
+		default:
+			fmt.Printf("Unknown command %q\n", args[0])
+		}
+	}
+}
+
+func backups(ctx context.Context, dbc *dbinterface.DatabaseConn, prefs config.Config) {
+	fmt.Printf(`Enter a command. Type menu to return to menu.
+  backup           save a backup to %s, keeping the newest %d
+  backup <file>    save a backup to a file
+  list             list the backups in %s
+  restore <file>   replace the whole database with a backup
+`, prefs.BackupDir, prefs.BackupKeep, prefs.BackupDir)
+	for {
+		line, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		args := append(strings.Fields(line), "")
+		switch args[0] {
+		case "menu":
+			return
+		case "backup":
+			if args[1] == "" {
+				var path string
+				path, err = backup.Save(ctx, prefs.BackupDir, func(ctx context.Context, w io.Writer) error {
+					return dbinterface.Backup(ctx, dbc, w)
+				})
+				if err == nil {
+					fmt.Printf("Saved %s\n", path)
+					err = backup.Rotate(prefs.BackupDir, prefs.BackupKeep)
+				}
+				break
+			}
+			err = writeBackup(ctx, dbc, args[1])
+		case "list":
+			var entries []os.DirEntry
+			entries, err = os.ReadDir(prefs.BackupDir)
+			for _, entry := range entries {
+				fmt.Println(filepath.Join(prefs.BackupDir, entry.Name()))
+			}
+		case "restore":
+			fmt.Printf("This replaces every card, deck and review with the content of %s. Type yes to continue.\n", args[1])
+			var answer string
+			if answer, err = readLine(); err != nil || answer != "yes" {
+				break
+			}
+			err = restoreBackup(ctx, dbc, args[1])
+			if err == nil {
+				fmt.Printf("Restored %s\n", args[1])
+			}
+		default:
+			fmt.Printf("Unknown command %q\n", args[0])
+		}
+		if err != nil {
+			log.Printf("%s error: %v", args[0], err)
+		}
+	}
+}
+
+// writeBackup writes a backup to path, readable by its owner only since it
+// holds password and token hashes.
+func writeBackup(ctx context.Context, dbc *dbinterface.DatabaseConn, path string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := dbinterface.Backup(ctx, dbc, file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func restoreBackup(ctx context.Context, dbc *dbinterface.DatabaseConn, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return dbinterface.RestoreBackup(ctx, dbc, file)
+}
+
+// serve runs without the menu until it is interrupted, serving the HTTP API
+// and saving scheduled backups.
+func serve(ctx context.Context, dbc *dbinterface.DatabaseConn, dictionary dict.Dictionary, prefs config.Config) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	every, err := prefs.BackupEvery()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	if prefs.ListenAddr != "" {
+		server := &http.Server{
+			Addr:              prefs.ListenAddr,
+			Handler:           api.New(dbc, dictionary, api.Options{Signup: prefs.Signup, Timeout: opTimeout}),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			log.Printf("Serving the API on %s", prefs.ListenAddr)
+			if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("API error: %v", err)
+			}
+		}()
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			server.Shutdown(shutdownCtx)
+		}()
+	}
+	log.Printf("Saving a backup to %s every %s", prefs.BackupDir, every)
+	backup.Schedule(ctx, prefs.BackupDir, every, prefs.BackupKeep, func(ctx context.Context, w io.Writer) error {
+		return dbinterface.Backup(ctx, dbc, w)
+	})
+}
+
+func library(ctx context.Context, dbc *dbinterface.DatabaseConn, dictionary dict.Dictionary) {
+	fmt.Println(`Enter a command. Type menu to return to menu.
+  list             list the cards other users share
+  add <id>         add a shared card to your cards
+  share <term>     share one of your cards
+  unshare <term>   stop sharing one of your cards`)
+	for {
+		line, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		args := append(strings.Fields(line), "")
+		ctx, cancel := operation(ctx)
+		switch args[0] {
+		case "menu":
+			cancel()
+			return
+		case "list":
+			var cards map[int64]dbinterface.LibraryCard
+			cards, err = dbinterface.Library(ctx, dbc)
+			if err == nil && len(cards) == 0 {
+				fmt.Println("Nobody shares any cards.")
+			}
+			for id, card := range cards {
+				fmt.Printf("%d: %s %s (shared by %s)\n", id, card.Term, card.Definition, card.Owner)
+			}
+		case "add":
+			var id int64
+			if id, err = strconv.ParseInt(args[1], 10, 64); err != nil {
+				break
+			}
+			var ids []int64
+			ids, err = dbinterface.AddFromLibrary(ctx, dbc, id, dictionary)
+			if err == nil {
+				fmt.Printf("Added IDs: %v\n", ids)
+			}
+		case "share", "unshare":
+			err = dbinterface.Share(ctx, dbc, args[1], args[0] == "share")
+		default:
+			fmt.Printf("Unknown command %q\n", args[0])
+		}
+		cancel()
+		if err != nil {
+			log.Printf("%s error: %v", args[0], err)
+		}
+	}
+}
+
+func apiAccess(ctx context.Context, dbc *dbinterface.DatabaseConn) {
+	fmt.Println(`Enter a command. Type menu to return to menu.
+  password <password>             set the password you log in to the API with
+  token read|write                create an API token for scripts
+  revoke <token>                  make an API token invalid
+  grant <user> read|write         let another user read or change your cards
+  ungrant <user>                  take back what you granted a user`)
+	for {
+		line, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		args := append(strings.Fields(line), "", "")
+		ctx, cancel := operation(ctx)
+		switch args[0] {
+		case "menu":
+			cancel()
+			return
+		case "password":
+			if err = dbinterface.SetPassword(ctx, dbc, args[1]); err == nil {
+				fmt.Println("Password set.")
+			}
+		case "token":
+			var permission dbinterface.Permission
+			if permission, err = dbinterface.ParsePermission(args[1]); err != nil {
+				break
+			}
+			var token string
+			token, err = dbinterface.CreateToken(ctx, dbc, dbinterface.APIToken, permission, 0)
+			if err == nil {
+				fmt.Printf("Token: %s\nIt is not shown again. Send it as \"Authorization: Bearer <token>\".\n", token)
+			}
+		case "revoke":
+			err = dbinterface.RevokeToken(ctx, dbc, args[1])
+		case "grant":
+			var permission dbinterface.Permission
+			if permission, err = dbinterface.ParsePermission(args[2]); err != nil {
+				break
+			}
+			err = dbinterface.Grant(ctx, dbc, args[1], permission)
+		case "ungrant":
+			err = dbinterface.RevokeGrant(ctx, dbc, args[1])
+		default:
+			fmt.Printf("Unknown command %q\n", args[0])
+		}
+		cancel()
+		if err != nil {
+			log.Printf("%s error: %v", args[0], err)
+		}
+	}
+}
+
+func trash(ctx context.Context, dbc *dbinterface.DatabaseConn) {
+	fmt.Println(`Enter a command. Type menu to return to menu.
+  list             list the terms in the trash
+  restore <term>   take a term out of the trash
+  purge <term>     delete a term in the trash for good
+  empty            delete every term in the trash for good`)
+	for {
+		line, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		args := append(strings.Fields(line), "")
+		ctx, cancel := operation(ctx)
+		switch args[0] {
+		case "menu":
+			cancel()
+			return
+		case "list":
+			var trashed map[int64]dbinterface.TrashedTerm
+			trashed, err = dbinterface.Trash(ctx, dbc)
+			if err == nil && len(trashed) == 0 {
+				fmt.Println("The trash is empty.")
+			}
+			for id, term := range trashed {
+				fmt.Printf("%d: %s %s (deleted %s)\n", id, term.Term, term.Definition, term.DeletedAt.Format("2006-01-02 15:04"))
+			}
+		case "restore":
+			err = dbinterface.Restore(ctx, dbc, args[1])
+		case "purge":
+			err = dbinterface.Purge(ctx, dbc, args[1])
+		case "empty":
+			err = dbinterface.EmptyTrash(ctx, dbc)
+		default:
+			fmt.Printf("Unknown command %q\n", args[0])
+		}
+		cancel()
+		if err != nil {
+			log.Printf("%s error: %v", args[0], err)
+		}
+	}
+}
+
+func leeches(ctx context.Context, dbc *dbinterface.DatabaseConn, dictionary dict.Dictionary) {
+	opCtx, cancel := operation(ctx)
+	found, err := dbinterface.Leeches(opCtx, dbc)
+	cancel()
+	if err != nil {
+		log.Printf("Leeches error: %v", err)
+		return
+	}
+	if len(found) == 0 {
+		fmt.Println("No leeches.")
+		return
+	}
+	for _, leech := range found {
+		fmt.Printf("%s, failed %d times. Try to:\n", leech.Term, leech.Lapses)
+		for _, suggestion := range leech.Suggestions {
+			fmt.Printf("  - %s\n", suggestion)
+		}
+	}
+	fmt.Println(`Enter a command. Type menu to return to menu.
+  split <term>   add cards for the parts of a leech
+  reset <term>   clear a leech's lapses and quiz it again`)
+	for {
+		line, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		args := append(strings.Fields(line), "")
+		ctx, cancel := operation(ctx)
+		switch args[0] {
+		case "menu":
+			cancel()
+			return
+		case "split":
+			var ids []int64
+			ids, err = dbinterface.SplitLeech(ctx, dbc, args[1], dictionary)
+			fmt.Printf("Added %d cards\n", len(ids))
+		case "reset":
+			err = dbinterface.ResetLeech(ctx, dbc, args[1])
+		default:
+			fmt.Printf("Unknown command %q\n", args[0])
+		}
+		cancel()
+		if err != nil {
+			log.Printf("%s error: %v", args[0], err)
+		}
+	}
+}
+
+func statistics(ctx context.Context, dbc *dbinterface.DatabaseConn) {
+	fmt.Println("1. Text")
+	fmt.Println("2. JSON")
+	choice, ok := scanChoice(2)
+	if !ok {
+		return
+	}
+	ctx, cancel := operation(ctx)
+	defer cancel()
+	report, err := dbinterface.Stats(ctx, dbc)
+	if err != nil {
+		log.Printf("Stats error: %v", err)
+		return
+	}
+	if choice == 1 {
+		err = report.WriteText(os.Stdout)
+	} else {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(report)
+	}
+	if err != nil {
+		log.Printf("Stats error: %v", err)
+	}
+}
+
+func settings(prefs *config.Config, prefsPath string) {
+	fmt.Println("Select the setting to change:")
+	fmt.Println("1. Pinyin display")
+	fmt.Println("2. Sort order")
+	fmt.Println("3. Tones in typed answers")
+	var input string
+	_, err := fmt.Scan(&input)
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+	switch input {
+	case "1":
+		fmt.Println("Select how pinyin is displayed:")
+		for i, style := range pinyin.Styles {
+			fmt.Printf("%d. %s (%s)\n", i+1, style, pinyin.Format("zhong1 wen2", style))
+		}
+		choice, ok := scanChoice(len(pinyin.Styles))
+		if !ok {
+			return
+		}
+		prefs.PinyinStyle = pinyin.Styles[choice-1]
+	case "2":
+		fmt.Println("Select how terms are sorted:")
+		fmt.Println("1. By id")
+		fmt.Println("2. By frequency")
+		choice, ok := scanChoice(2)
+		if !ok {
+			return
+		}
+		prefs.SortByFrequency = choice == 2
+	case "3":
+		fmt.Println("Select how typed pinyin is graded:")
+		fmt.Println("1. Tones must be right")
+		fmt.Println("2. Ignore tones")
+		choice, ok := scanChoice(2)
+		if !ok {
+			return
+		}
+		prefs.IgnoreTones = choice == 2
+	default:
+		return
+	}
+	if err := prefs.Save(prefsPath); err != nil {
+		log.Printf("Settings error: %v", err)
+	}
+}
+
+// scanChoice reads a menu choice between 1 and n.
+func scanChoice(n int) (int, bool) {
+	var input string
+	_, err := fmt.Scan(&input)
+	if err != nil {
+		log.Printf("input error %v", err)
+		return 0, false
+	}
+	choice, err := strconv.Atoi(input)
+	if err != nil || choice < 1 || choice > n {
+		fmt.Printf("%q is not a valid choice\n", input)
+		return 0, false
+	}
+	return choice, true
+}
+
+// readLine reads a non-empty line from stdin, trimmed of spaces.
+func readLine() (string, error) {
+	for {
+		line, err := readRawLine()
+		if err != nil {
+			return "", err
+		}
+		if s := strings.TrimSpace(line); s != "" {
+			return s, nil
+		}
+	}
+}
+
+// readRawLine reads a line from stdin, which may be empty. It reads a byte
+// at a time, like fmt.Scan, so no input is buffered away from later fmt.Scan
+// calls.
+func readRawLine() (string, error) {
+	buf := make([]byte, 1)
+	var line strings.Builder
+	for {
+		if _, err := os.Stdin.Read(buf); err != nil {
+			return "", err
+		}
+		if buf[0] == '\n' {
+			return line.String(), nil
+		}
+		line.WriteByte(buf[0])
+	}
+}
+
+// splitArgs splits a command line on spaces, keeping double quoted
+// arguments such as "textbook ch.5" together.
+func splitArgs(line string) []string {
+	var args []string
+	for i, part := range strings.Split(line, `"`) {
+		if i%2 == 1 {
+			args = append(args, part)
+		} else {
+			args = append(args, strings.Fields(part)...)
+		}
+	}
+	return args
+}
+
+func printDecks(ctx context.Context, dbc *dbinterface.DatabaseConn) {
+	decks, err := dbinterface.ListDecks(ctx, dbc)
+	if err != nil {
+		log.Printf("List decks error: %v", err)
+		return
+	}
+	if len(decks) == 0 {
+		fmt.Println("No decks.")
+	}
+	for _, deck := range decks {
+		fmt.Printf("%s (%s)\n", deck.Name, deck.Language)
+	}
+}
+
+func decksAndTags(ctx context.Context, dbc *dbinterface.DatabaseConn, view display) {
+	fmt.Println(`Enter a command. Quote names containing spaces. Type menu to return to menu.
+  decks                       list decks
+  create <deck>               create a deck
+  rename <deck> <new name>    rename a deck
+  delete <deck>               delete a deck, keeping its terms
+  add <deck> <term>           put a term in a deck
+  remove <deck> <term>        take a term out of a deck
+  tag <term> <tag>            tag a term
+  untag <term> <tag>          remove a tag from a term
+  list <deck|-> [tags]        list terms, e.g. list - "HSK3 and not work"
+  find <term> <deck|-> [tags] find terms in a deck or matching tags
+  radical <radical>           list characters with a radical
+  component <component>       list characters containing a component
+  directions <deck> [dir...]  show or set a deck's card directions, from
+                              term-definition, definition-term,
+                              pinyin-hanzi and hanzi-pinyin`)
+	for {
+		line, err := readLine()
+		if err != nil {
+			log.Printf("input error %v", err)
+			return
+		}
+		args := splitArgs(line)
+		if len(args) == 0 {
+			continue
+		}
+		arg := func(i int) string {
+			if i < len(args) {
+				return args[i]
+			}
+			return ""
+		}
+		filter := func(i int) dbinterface.Filter {
+			f := dbinterface.Filter{Deck: arg(i), Tags: strings.Join(args[min(i+1, len(args)):], " ")}
+			if f.Deck == "-" {
+				f.Deck = ""
+			}
+			return f
+		}
+
+		ctx, cancel := operation(ctx)
+		switch args[0] {
+		case "menu":
+			cancel()
+			return
+		case "decks":
+			printDecks(ctx, dbc)
+		case "create":
+			_, err = dbinterface.CreateDeck(ctx, dbc, arg(1))
+		case "rename":
+			err = dbinterface.RenameDeck(ctx, dbc, arg(1), arg(2))
+		case "delete":
+			err = dbinterface.DeleteDeck(ctx, dbc, arg(1))
+		case "add":
+			err = dbinterface.AddToDeck(ctx, dbc, arg(1), arg(2))
+		case "remove":
+			err = dbinterface.RemoveFromDeck(ctx, dbc, arg(1), arg(2))
+		case "tag":
+			err = dbinterface.Tag(ctx, dbc, arg(1), arg(2))
+		case "untag":
+			err = dbinterface.Untag(ctx, dbc, arg(1), arg(2))
+		case "list":
+			var terms map[int64]dbinterface.TermDef
+			terms, err = dbinterface.ListFiltered(ctx, dbc, filter(1))
+			printTerms(terms, view)
+		case "find":
+			var terms map[int64]dbinterface.TermDef
+			terms, err = dbinterface.FindFiltered(ctx, dbc, arg(1), filter(2))
+			printTerms(terms, view)
+		case "radical", "component":
+			f := dbinterface.Filter{Radical: arg(1)}
+			if args[0] == "component" {
+				f = dbinterface.Filter{Component: arg(1)}
+			}
+			var terms map[int64]dbinterface.TermDef
+			terms, err = dbinterface.ListFiltered(ctx, dbc, f)
+			printTerms(terms, view)
+		case "directions":
+			err = deckDirections(ctx, dbc, arg(1), args[min(2, len(args)):])
+		default:
+			fmt.Printf("Unknown command %q\n", args[0])
+		}
+		cancel()
+		if err != nil {
+			log.Printf("%s error: %v", args[0], err)
+		}
+	}
+}
+
+func hskDeck(ctx context.Context, dbc *dbinterface.DatabaseConn, dictionary dict.Dictionary, hsk dict.HSKLevels) {
+	fmt.Println("Enter the HSK level to make a deck of the words you do not have yet:")
+	var input string
+	_, err := fmt.Scan(&input)
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+	level, err := strconv.Atoi(input)
+	if err != nil {
+		fmt.Printf("%q is not a level\n", input)
+		return
+	}
+	words := hsk.Words(level)
+	if len(words) == 0 {
+		fmt.Printf("No HSK words loaded for level %d\n", level)
+		return
+	}
+	ctx, cancel := operation(ctx)
+	defer cancel()
+	ids, err := dbinterface.AddNewWordsToDeck(ctx, dbc, dict.HSKTag(level), words, dictionary)
+	if err != nil {
+		log.Printf("HSK deck error: %v", err)
+	}
+	fmt.Printf("Added %d cards to deck %s\n", len(ids), dict.HSKTag(level))
+}
+
+func frequentDeck(ctx context.Context, dbc *dbinterface.DatabaseConn, dictionary dict.Dictionary, ranks dict.FrequencyRanks) {
+	if len(ranks) == 0 {
+		fmt.Println("No frequency list loaded.")
+		return
+	}
+	fmt.Println("Enter the number of words and the deck to put them in (e.g. 20 frequent):")
+	var input, deckName string
+	_, err := fmt.Scan(&input, &deckName)
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+	n, err := strconv.Atoi(input)
+	if err != nil || n < 1 {
+		fmt.Printf("%q is not a number of words\n", input)
+		return
+	}
+	ctx, cancel := operation(ctx)
+	defer cancel()
+	words, err := dbinterface.NextUnknownWords(ctx, dbc, ranks.Words(), n, dictionary)
+	if err != nil {
+		log.Printf("Frequent words error: %v", err)
+		return
+	}
+	fmt.Printf("Next most frequent words: %s\n", strings.Join(words, " "))
+	ids, err := dbinterface.AddNewWordsToDeck(ctx, dbc, deckName, words, dictionary)
+	if err != nil {
+		log.Printf("Frequent words error: %v", err)
+	}
+	fmt.Printf("Added %d cards to deck %s\n", len(ids), deckName)
+}
+
+func strokeOrder(ctx context.Context, dbc *dbinterface.DatabaseConn, lang language.Language, graphicsPath, outputDir string) {
+	fmt.Println("Enter the card to draw stroke order diagrams for:")
+	var input string
+	_, err := fmt.Scan(&input)
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+	opCtx, cancel := operation(ctx)
+	terms, err := dbinterface.Find(opCtx, dbc, input)
+	cancel()
+	if err != nil {
+		log.Printf("Stroke order error: %v", err)
+		return
+	}
+	isCard := false
+	for _, termDef := range terms {
+		isCard = isCard || termDef.Term == input
+	}
+	if !isCard {
+		fmt.Printf("%s is not a card\n", input)
+		return
+	}
+
+	chars, err := strokes.Load(graphicsPath, lang.Tokenize(input))
+	if err != nil {
+		log.Printf("Stroke order error: %v", err)
+		return
+	}
+	for _, c := range lang.Tokenize(input) {
+		character, ok := chars[c]
+		if !ok {
+			fmt.Printf("No stroke data for %s\n", c)
+			continue
+		}
+		paths, err := character.WriteFiles(outputDir, 6)
+		if err != nil {
+			log.Printf("Stroke order error: %v", err)
+			return
+		}
+		fmt.Printf("Wrote %s\n", strings.Join(paths, ", "))
+	}
+}
+
+func addEntry(registry *dict.Registry, lang language.Language) {
+	fmt.Println("Enter the term for your dictionary entry:")
+	term, err := readLine()
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+	fmt.Println("Enter its reading (e.g. ni3 hao3 or nǐ hǎo):")
+	reading, err := readLine()
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+	if _, chinese := lang.(language.Chinese); chinese && !strings.ContainsAny(reading, "12345") {
+		reading = pinyin.MarksToNumbers(reading)
+	}
+	fmt.Println("Enter its definition:")
+	definition, err := readLine()
+	if err != nil {
+		log.Printf("input error %v", err)
+		return
+	}
+
+	entry := dict.DictionaryEntry{Simplified: term, Pinyin: reading, English: definition}
+	if err := registry.AddUserEntry(entry); err != nil {
+		log.Printf("Add dictionary entry error: %v", err)
+		return
+	}
+	fmt.Printf("Added %q to your dictionary\n", term)
+}
+
+// userConn returns a connection for the user called name, creating the user
+// the first time.
+func userConn(ctx context.Context, dbc *dbinterface.DatabaseConn, name string) (*dbinterface.DatabaseConn, error) {
+	userDbc, err := dbinterface.ForUser(ctx, dbc, name)
+	if !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		return userDbc, err
+	}
+	if _, err := dbinterface.CreateUser(ctx, dbc, name); err != nil && !errors.As(err, new(*dbinterface.ErrUserExists)) {
+		return nil, err
+	}
+	log.Printf("Created user %q", name)
+	return dbinterface.ForUser(ctx, dbc, name)
 }
 
 func main() {
+	ctx := context.Background()
 	cfg := mysql.Config{
 		User:   os.Getenv("DBUSER"),
 		Passwd: os.Getenv("DBPASS"),
@@ -104,16 +1359,74 @@ func main() {
 		Addr:   "127.0.0.1:3306",
 		DBName: "flashcards",
 	}
+	prefsPath, err := config.DefaultPath()
+	if err != nil {
+		log.Fatalf("Config path error: %v", err)
+	}
+	prefs, err := config.Load(prefsPath)
+	if err != nil {
+		log.Printf("Config error, using defaults: %v", err)
+	}
+	lang, err := prefs.StudyLanguage()
+	if err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+	if opTimeout, err = prefs.Timeout(); err != nil {
+		log.Fatalf("Config error: %v", err)
+	}
+
 	tableName := "terms"
-	dbc, err := dbinterface.Connect(cfg, tableName)
+	dbc, err := dbinterface.ConnectWithLanguage(ctx, cfg, tableName, lang)
 	if err != nil {
 		log.Fatalf("Connect error: %v", err)
 	}
+	if prefs.User != "" {
+		opCtx, cancel := operation(ctx)
+		dbc, err = userConn(opCtx, dbc, prefs.User)
+		cancel()
+		if err != nil {
+			log.Fatalf("User error: %v", err)
+		}
+	}
+	dbinterface.SetLeechThreshold(dbc, prefs.LeechThreshold)
 
-	dictMap, err := dict.ParseDict("dict/cedict_ts.u8")
+	userDictPath := prefs.UserDictionary
+	if userDictPath == "" {
+		userDictPath = filepath.Join(filepath.Dir(prefsPath), "user_dict.tsv")
+	}
+	registry, err := dict.NewRegistry(prefs.Dictionaries(lang), userDictPath)
 	if err != nil {
 		log.Fatalf("Dictionary parse error: %v", err)
 	}
+	hsk := make(dict.HSKLevels)
+	if _, chinese := lang.(language.Chinese); chinese {
+		hsk, err = dict.LoadHSK(prefs.HSK())
+		if err != nil {
+			log.Fatalf("HSK list error: %v", err)
+		}
+		registry.AnnotateHSK(hsk)
+
+		decompositions, err := dict.ParseMakeMeAHanzi(prefs.HanziDictionary, prefs.HanziGraphics)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Fatalf("Character data error: %v", err)
+		}
+		registry.AnnotateDecompositions(decompositions)
+	}
+	ranks, err := dict.ParseFrequency(prefs.FrequencyList)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Fatalf("Frequency list error: %v", err)
+	}
+	registry.AnnotateFrequency(ranks)
+
+	index, err := sentences.LoadTSV(prefs.SentencesFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Fatalf("Example sentences error: %v", err)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serve(ctx, dbc, registry, prefs)
+		return
+	}
 
 	for {
 		fmt.Println("Select the operation you want to perform:")
@@ -121,6 +1434,25 @@ func main() {
 		fmt.Println("2. Delete")
 		fmt.Println("3. Find")
 		fmt.Println("4. List")
+		fmt.Println("5. Settings")
+		fmt.Println("6. Add dictionary entry")
+		fmt.Println("7. Decks and tags")
+		fmt.Println("8. HSK level deck")
+		fmt.Println("9. Most frequent new words deck")
+		fmt.Println("10. Stroke order diagrams")
+		fmt.Println("11. Pin an example sentence")
+		fmt.Println("12. Cloze cards")
+		fmt.Println("13. Typed-answer quiz")
+		fmt.Println("14. Review cards")
+		fmt.Println("15. Statistics")
+		fmt.Println("16. Leeches")
+		fmt.Println("17. Trash")
+		fmt.Println("18. Batch add, delete and edit")
+		fmt.Println("19. Undo and redo")
+		fmt.Println("20. Card history")
+		fmt.Println("21. Backup and restore")
+		fmt.Println("22. Shared library")
+		fmt.Println("23. API access")
 		fmt.Println("Any other input: Exit")
 		var input string
 		_, err := fmt.Scan(&input)
@@ -129,13 +1461,51 @@ func main() {
 		}
 		switch input {
 		case "1":
-			add(dbc, dictMap)
+			add(ctx, dbc, newDisplay(registry, lang, index, prefs))
 		case "2":
-			delete(dbc)
+			delete(ctx, dbc)
 		case "3":
-			find(dbc)
+			find(ctx, dbc, newDisplay(registry, lang, index, prefs))
 		case "4":
-			list(dbc)
+			list(ctx, dbc, newDisplay(registry, lang, index, prefs))
+		case "5":
+			settings(&prefs, prefsPath)
+		case "6":
+			addEntry(registry, lang)
+		case "7":
+			decksAndTags(ctx, dbc, newDisplay(registry, lang, index, prefs))
+		case "8":
+			hskDeck(ctx, dbc, registry, hsk)
+		case "9":
+			frequentDeck(ctx, dbc, registry, ranks)
+		case "10":
+			strokeOrder(ctx, dbc, lang, prefs.HanziGraphics, prefs.StrokeOrderDir)
+		case "11":
+			exampleSentences(ctx, dbc, newDisplay(registry, lang, index, prefs))
+		case "12":
+			clozeCards(ctx, dbc, newDisplay(registry, lang, index, prefs), quiz.Options{IgnoreTones: prefs.IgnoreTones})
+		case "13":
+			typedQuiz(ctx, dbc, newDisplay(registry, lang, index, prefs), quiz.Options{IgnoreTones: prefs.IgnoreTones})
+		case "14":
+			review(ctx, dbc, newDisplay(registry, lang, index, prefs))
+		case "15":
+			statistics(ctx, dbc)
+		case "16":
+			leeches(ctx, dbc, registry)
+		case "17":
+			trash(ctx, dbc)
+		case "18":
+			batch(ctx, dbc, registry)
+		case "19":
+			undoRedo(ctx, dbc)
+		case "20":
+			history(ctx, dbc)
+		case "21":
+			backups(ctx, dbc, prefs)
+		case "22":
+			library(ctx, dbc, registry)
+		case "23":
+			apiAccess(ctx, dbc)
 		default:
 			return
 		}
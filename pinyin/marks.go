@@ -0,0 +1,172 @@
+package pinyin
+
+import (
+	"strings"
+	"unicode"
+)
+
+// toneMarks maps each vowel to its tone 1-4 forms.
+var toneMarks = map[rune][4]rune{
+	'a': {'ā', 'á', 'ǎ', 'à'},
+	'e': {'ē', 'é', 'ě', 'è'},
+	'i': {'ī', 'í', 'ǐ', 'ì'},
+	'o': {'ō', 'ó', 'ǒ', 'ò'},
+	'u': {'ū', 'ú', 'ǔ', 'ù'},
+	'ü': {'ǖ', 'ǘ', 'ǚ', 'ǜ'},
+	'A': {'Ā', 'Á', 'Ǎ', 'À'},
+	'E': {'Ē', 'É', 'Ě', 'È'},
+	'I': {'Ī', 'Í', 'Ǐ', 'Ì'},
+	'O': {'Ō', 'Ó', 'Ǒ', 'Ò'},
+	'U': {'Ū', 'Ú', 'Ǔ', 'Ù'},
+	'Ü': {'Ǖ', 'Ǘ', 'Ǚ', 'Ǜ'},
+}
+
+// combiningMarks are used for syllabic nasals such as m2 and ng3, which have
+// no precomposed forms.
+var combiningMarks = [4]rune{'̄', '́', '̌', '̀'}
+
+type markedVowel struct {
+	base rune
+	tone int
+}
+
+var unmarked = func() map[rune]markedVowel {
+	m := make(map[rune]markedVowel)
+	for base, marks := range toneMarks {
+		for i, r := range marks {
+			m[r] = markedVowel{base: base, tone: i + 1}
+		}
+	}
+	return m
+}()
+
+type syllable struct {
+	body   string
+	tone   int
+	erhua  bool
+	suffix string
+}
+
+// splitNumbered splits a numbered syllable such as "hao3" or "nu:3" into its
+// letters and tone. ok is false for tokens that are not numbered pinyin, like
+// punctuation or the Latin letters CC-CEDICT uses in some entries.
+func splitNumbered(token string) (syllable, bool) {
+	if len(token) < 2 {
+		return syllable{}, false
+	}
+	last := token[len(token)-1]
+	if last < '1' || last > '5' {
+		return syllable{}, false
+	}
+	body := normalizeU(token[:len(token)-1])
+	for _, r := range body {
+		if !unicode.IsLetter(r) {
+			return syllable{}, false
+		}
+	}
+	s := syllable{body: body, tone: int(last - '0')}
+	lower := strings.ToLower(body)
+	if len(lower) > 2 && lower != "er" && strings.HasSuffix(lower, "r") {
+		s.body = body[:len(body)-1]
+		s.suffix = body[len(body)-1:]
+		s.erhua = true
+	}
+	return s, true
+}
+
+// normalizeU rewrites the u: and v spellings of ü used in numbered pinyin.
+func normalizeU(s string) string {
+	return strings.NewReplacer("u:", "ü", "U:", "Ü", "v", "ü", "V", "Ü").Replace(s)
+}
+
+// markIndex returns the byte index of the rune carrying the tone mark:
+// a or e if present, the o of ou, and otherwise the last vowel.
+func markIndex(body string) int {
+	lower := strings.ToLower(body)
+	if i := strings.IndexAny(lower, "ae"); i >= 0 {
+		return i
+	}
+	if i := strings.Index(lower, "ou"); i >= 0 {
+		return i
+	}
+	return strings.LastIndexAny(lower, "iouü")
+}
+
+func addMark(body string, tone int) string {
+	if tone < 1 || tone > 4 {
+		return body
+	}
+	i := markIndex(body)
+	if i < 0 {
+		// Syllabic nasals (m, n, ng, hm, hng) carry the mark on the nasal.
+		i = strings.IndexAny(strings.ToLower(body), "mn")
+		if i < 0 {
+			return body
+		}
+		return body[:i+1] + string(combiningMarks[tone-1]) + body[i+1:]
+	}
+	r := []rune(body[i:])
+	return body[:i] + string(toneMarks[r[0]][tone-1]) + string(r[1:])
+}
+
+// NumbersToMarks converts CC-CEDICT style numbered pinyin ("ni3 hao3") to
+// pinyin with tone marks ("nǐ hǎo"). A standalone "r5" is joined onto the
+// preceding syllable. Tokens that are not numbered pinyin are kept as is.
+func NumbersToMarks(numbered string) string {
+	var out []string
+	for _, token := range strings.Fields(numbered) {
+		s, ok := splitNumbered(token)
+		if !ok {
+			out = append(out, token)
+			continue
+		}
+		if strings.EqualFold(s.body, "r") && s.tone == 5 && len(out) > 0 {
+			out[len(out)-1] += s.body
+			continue
+		}
+		out = append(out, addMark(s.body, s.tone)+s.suffix)
+	}
+	return strings.Join(out, " ")
+}
+
+// MarksToNumbers converts pinyin with tone marks back to numbered pinyin,
+// writing ü as u: like CC-CEDICT. Unmarked syllables get the neutral tone 5
+// and erhua syllables such as "wánr" are split into "wan2 r5".
+func MarksToNumbers(marked string) string {
+	var out []string
+	for _, token := range strings.Fields(marked) {
+		if strings.IndexFunc(token, unicode.IsLetter) < 0 {
+			out = append(out, token)
+			continue
+		}
+		tone := 5
+		var b strings.Builder
+		for _, r := range token {
+			if v, ok := unmarked[r]; ok {
+				tone = v.tone
+				r = v.base
+			}
+			for i, m := range combiningMarks {
+				if r == m {
+					tone = i + 1
+				}
+			}
+			if unicode.Is(unicode.Mn, r) {
+				continue
+			}
+			b.WriteRune(r)
+		}
+		body := b.String()
+		erhua := false
+		if lower := strings.ToLower(body); len(lower) > 2 && lower != "er" && strings.HasSuffix(lower, "r") {
+			body = body[:len(body)-1]
+			erhua = true
+		}
+		body = strings.NewReplacer("ü", "u:", "Ü", "U:").Replace(body)
+		out = append(out, body+string(rune('0'+tone)))
+		if erhua {
+			out = append(out, "r5")
+		}
+	}
+	return strings.Join(out, " ")
+}
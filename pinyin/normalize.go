@@ -0,0 +1,40 @@
+package pinyin
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize rewrites pinyin typed in any of the usual ways (ni3 hao3,
+// ni3hao3, nǐhǎo, Ni3 Hao3, lv4, lu:4) to one comparable form: the
+// lowercase letters, with ü as u:, followed by the tones in order. Neutral
+// tones are left out, so "shi5" and "shi" compare equal.
+func Normalize(p string) string {
+	var letters, tones strings.Builder
+	for _, r := range strings.ToLower(p) {
+		if v, ok := unmarked[r]; ok {
+			r = v.base
+			tones.WriteRune(rune('0' + v.tone))
+		}
+		for i, m := range combiningMarks {
+			if r == m {
+				tones.WriteRune(rune('1' + i))
+			}
+		}
+		switch {
+		case r >= '1' && r <= '4':
+			tones.WriteRune(r)
+		case r == 'ü' || r == 'v':
+			letters.WriteString("u:")
+		case r == ':' && strings.HasSuffix(letters.String(), "u:"):
+		case unicode.IsLetter(r) || r == ':':
+			letters.WriteRune(r)
+		}
+	}
+	return letters.String() + tones.String()
+}
+
+// StripTones removes the tones from normalized pinyin.
+func StripTones(normalized string) string {
+	return strings.TrimRight(normalized, "1234")
+}
@@ -0,0 +1,78 @@
+package pinyin
+
+import "testing"
+
+func TestNumbersToMarks(t *testing.T) {
+	tests := map[string]string{
+		"ni3 hao3":        "nǐ hǎo",
+		"Bei3 jing1":      "Běi jīng",
+		"nu:3 er2":        "nǚ ér",
+		"lv4":             "lǜ",
+		"xiu1 gui4":       "xiū guì",
+		"duo1 shao5":      "duō shao",
+		"yi1 dian3 r5":    "yī diǎnr",
+		"nar3":            "nǎr",
+		"m2":              "m\u0301",
+		"A A zhi4":        "A A zhì",
+		"ka3 la1 O K":     "kǎ lā O K",
+		"xue2 xi2 · mou3": "xué xí · mǒu",
+	}
+	for in, want := range tests {
+		if got := NumbersToMarks(in); got != want {
+			t.Errorf("NumbersToMarks(%q) = %q; wanted %q", in, got, want)
+		}
+	}
+}
+
+func TestMarksToNumbers(t *testing.T) {
+	tests := map[string]string{
+		"nǐ hǎo":   "ni3 hao3",
+		"nǚ ér":    "nu:3 er2",
+		"duō shao": "duo1 shao5",
+		"wánr":     "wan2 r5",
+		"m\u0301":  "m2",
+	}
+	for in, want := range tests {
+		if got := MarksToNumbers(in); got != want {
+			t.Errorf("MarksToNumbers(%q) = %q; wanted %q", in, got, want)
+		}
+	}
+}
+
+func TestZhuyin(t *testing.T) {
+	tests := map[string]string{
+		"ni3 hao3":       "ㄋㄧˇ ㄏㄠˇ",
+		"zhi1 shi5":      "ㄓ ˙ㄕ",
+		"xue2 xi2":       "ㄒㄩㄝˊ ㄒㄧˊ",
+		"you3 yong4":     "ㄧㄡˇ ㄩㄥˋ",
+		"wei4 weng1":     "ㄨㄟˋ ㄨㄥ",
+		"liu2 gui4 lun2": "ㄌㄧㄡˊ ㄍㄨㄟˋ ㄌㄨㄣˊ",
+		"nu:3 er2":       "ㄋㄩˇ ㄦˊ",
+		"wan2 r5":        "ㄨㄢˊㄦ",
+		"zhong1 guo2":    "ㄓㄨㄥ ㄍㄨㄛˊ",
+		"qu4 yuan2":      "ㄑㄩˋ ㄩㄢˊ",
+	}
+	for in, want := range tests {
+		if got := NumbersToZhuyin(in); got != want {
+			t.Errorf("NumbersToZhuyin(%q) = %q; wanted %q", in, got, want)
+		}
+		if got := ZhuyinToNumbers(want); got != in {
+			t.Errorf("ZhuyinToNumbers(%q) = %q; wanted %q", want, got, in)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	want := Normalize("ni3 hao3")
+	for _, in := range []string{"ni3hao3", "Ni3 Hao3", "nǐ hǎo", " nǐhǎo "} {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q; wanted %q", in, got, want)
+		}
+	}
+	if got, want := Normalize("lv4 shi5"), Normalize("lǜ shi"); got != want {
+		t.Errorf("Got %q; wanted %q", got, want)
+	}
+	if got := StripTones(Normalize("nǚ ér")); got != "nu:er" {
+		t.Errorf("Got %q; wanted nu:er", got)
+	}
+}
@@ -0,0 +1,34 @@
+package pinyin
+
+import "fmt"
+
+// Style is how pinyin is displayed to the user.
+type Style string
+
+const (
+	Numbers Style = "numbers"
+	Marks   Style = "marks"
+	Zhuyin  Style = "zhuyin"
+)
+
+var Styles = []Style{Numbers, Marks, Zhuyin}
+
+func ParseStyle(s string) (Style, error) {
+	for _, style := range Styles {
+		if string(style) == s {
+			return style, nil
+		}
+	}
+	return "", fmt.Errorf("unknown pinyin style %q", s)
+}
+
+// Format converts CC-CEDICT numbered pinyin to the given style.
+func Format(numbered string, style Style) string {
+	switch style {
+	case Marks:
+		return NumbersToMarks(numbered)
+	case Zhuyin:
+		return NumbersToZhuyin(numbered)
+	}
+	return numbered
+}
@@ -0,0 +1,209 @@
+package pinyin
+
+import (
+	"strings"
+)
+
+var initials = map[string]string{
+	"b": "ㄅ", "p": "ㄆ", "m": "ㄇ", "f": "ㄈ",
+	"d": "ㄉ", "t": "ㄊ", "n": "ㄋ", "l": "ㄌ",
+	"g": "ㄍ", "k": "ㄎ", "h": "ㄏ",
+	"j": "ㄐ", "q": "ㄑ", "x": "ㄒ",
+	"zh": "ㄓ", "ch": "ㄔ", "sh": "ㄕ", "r": "ㄖ",
+	"z": "ㄗ", "c": "ㄘ", "s": "ㄙ",
+}
+
+// finals maps the full (unabbreviated) pinyin finals to zhuyin.
+var finals = map[string]string{
+	"": "", "a": "ㄚ", "o": "ㄛ", "e": "ㄜ", "ê": "ㄝ",
+	"ai": "ㄞ", "ei": "ㄟ", "ao": "ㄠ", "ou": "ㄡ",
+	"an": "ㄢ", "en": "ㄣ", "ang": "ㄤ", "eng": "ㄥ", "er": "ㄦ", "ong": "ㄨㄥ",
+	"i": "ㄧ", "ia": "ㄧㄚ", "io": "ㄧㄛ", "ie": "ㄧㄝ", "iao": "ㄧㄠ", "iou": "ㄧㄡ",
+	"ian": "ㄧㄢ", "in": "ㄧㄣ", "iang": "ㄧㄤ", "ing": "ㄧㄥ", "iong": "ㄩㄥ",
+	"u": "ㄨ", "ua": "ㄨㄚ", "uo": "ㄨㄛ", "uai": "ㄨㄞ", "uei": "ㄨㄟ",
+	"uan": "ㄨㄢ", "uen": "ㄨㄣ", "uang": "ㄨㄤ",
+	"ü": "ㄩ", "üe": "ㄩㄝ", "üan": "ㄩㄢ", "ün": "ㄩㄣ",
+}
+
+// syllabic initials are written with an i that has no zhuyin of its own.
+var syllabic = map[string]bool{"zh": true, "ch": true, "sh": true, "r": true, "z": true, "c": true, "s": true}
+
+// palatal initials write ü as u.
+var palatal = map[string]bool{"j": true, "q": true, "x": true}
+
+var zhuyinTones = [...]string{1: "", 2: "ˊ", 3: "ˇ", 4: "ˋ", 5: "˙"}
+
+var (
+	initialsReverse = reverse(initials)
+	finalsReverse   = reverse(finals)
+)
+
+func reverse(m map[string]string) map[string]string {
+	r := make(map[string]string, len(m))
+	for k, v := range m {
+		r[v] = k
+	}
+	return r
+}
+
+// splitInitial returns the initial and the full final of a lowercase pinyin
+// syllable, undoing the y/w spellings and the iu, ui and un abbreviations.
+func splitInitial(body string) (string, string) {
+	switch {
+	case strings.HasPrefix(body, "y"):
+		rest := body[1:]
+		switch {
+		case rest == "i" || rest == "in" || rest == "ing":
+			return "", rest
+		case strings.HasPrefix(rest, "u"):
+			return "", "ü" + rest[1:]
+		case strings.HasPrefix(rest, "ü"):
+			return "", rest
+		case rest == "ong":
+			return "", "iong"
+		}
+		return "", "i" + rest
+	case strings.HasPrefix(body, "w"):
+		switch body {
+		case "wu":
+			return "", "u"
+		case "weng":
+			return "", "ong"
+		}
+		return "", "u" + body[1:]
+	}
+
+	initial := ""
+	if len(body) > 2 && initials[body[:2]] != "" {
+		initial = body[:2]
+	} else if len(body) > 1 && initials[body[:1]] != "" {
+		initial = body[:1]
+	}
+	final := body[len(initial):]
+	switch {
+	case final == "i" && syllabic[initial]:
+		final = ""
+	case palatal[initial] && strings.HasPrefix(final, "u"):
+		final = "ü" + final[1:]
+	case final == "iu":
+		final = "iou"
+	case final == "ui":
+		final = "uei"
+	case final == "un":
+		final = "uen"
+	}
+	return initial, final
+}
+
+// NumbersToZhuyin converts numbered pinyin ("ni3 hao3") to zhuyin
+// ("ㄋㄧˇ ㄏㄠˇ"). Tokens it cannot convert are kept as is.
+func NumbersToZhuyin(numbered string) string {
+	var out []string
+	for _, token := range strings.Fields(numbered) {
+		s, ok := splitNumbered(token)
+		if !ok {
+			out = append(out, token)
+			continue
+		}
+		body := strings.ToLower(s.body)
+		if body == "r" && s.tone == 5 && len(out) > 0 {
+			out[len(out)-1] += "ㄦ"
+			continue
+		}
+		initial, final := splitInitial(body)
+		z, ok := finals[final]
+		if !ok || initial+final == "" {
+			out = append(out, token)
+			continue
+		}
+		z = initials[initial] + z
+		if s.tone == 5 {
+			z = zhuyinTones[5] + z
+		} else {
+			z += zhuyinTones[s.tone]
+		}
+		if s.erhua {
+			z += "ㄦ"
+		}
+		out = append(out, z)
+	}
+	return strings.Join(out, " ")
+}
+
+// ZhuyinToNumbers converts zhuyin back to numbered pinyin in CC-CEDICT form.
+func ZhuyinToNumbers(zhuyin string) string {
+	var out []string
+	for _, token := range strings.Fields(zhuyin) {
+		runes := []rune(token)
+		tone := 1
+		if len(runes) > 0 && string(runes[0]) == zhuyinTones[5] {
+			tone = 5
+			runes = runes[1:]
+		}
+		erhua := false
+		if len(runes) > 1 && runes[len(runes)-1] == 'ㄦ' {
+			erhua = true
+			runes = runes[:len(runes)-1]
+		}
+		for t := 2; t <= 4 && len(runes) > 0; t++ {
+			if string(runes[len(runes)-1]) == zhuyinTones[t] {
+				tone = t
+				runes = runes[:len(runes)-1]
+			}
+		}
+		if len(runes) == 0 {
+			out = append(out, token)
+			continue
+		}
+
+		initial, rest := initialsReverse[string(runes[0])], string(runes[1:])
+		if initial == "" {
+			rest = string(runes)
+		}
+		final, ok := finalsReverse[rest]
+		if !ok {
+			out = append(out, token)
+			continue
+		}
+		out = append(out, joinSyllable(initial, final)+string(rune('0'+tone)))
+		if erhua {
+			out = append(out, "r5")
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+// joinSyllable applies the pinyin spelling rules to an initial and full final.
+func joinSyllable(initial, final string) string {
+	if initial == "" {
+		switch {
+		case final == "ong":
+			return "weng"
+		case final == "i" || final == "in" || final == "ing":
+			return "y" + final
+		case strings.HasPrefix(final, "ü"):
+			return "yu" + strings.TrimPrefix(final, "ü")
+		case strings.HasPrefix(final, "i"):
+			return "y" + final[1:]
+		case final == "u":
+			return "wu"
+		case strings.HasPrefix(final, "u"):
+			return "w" + final[1:]
+		}
+		return final
+	}
+
+	switch {
+	case final == "" && syllabic[initial]:
+		final = "i"
+	case palatal[initial] && strings.HasPrefix(final, "ü"):
+		final = "u" + strings.TrimPrefix(final, "ü")
+	case final == "iou":
+		final = "iu"
+	case final == "uei":
+		final = "ui"
+	case final == "uen":
+		final = "un"
+	}
+	return initial + strings.ReplaceAll(final, "ü", "u:")
+}
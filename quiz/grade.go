@@ -0,0 +1,101 @@
+package quiz
+
+import (
+	"strings"
+
+	"github.com/flashcards/dict"
+	"github.com/flashcards/pinyin"
+)
+
+// Match is the part of a card that a typed answer matched.
+type Match int
+
+const (
+	NoMatch Match = iota
+	TermMatch
+	ReadingMatch
+	MeaningMatch
+)
+
+// Options controls how tolerant Grade is.
+type Options struct {
+	// IgnoreTones accepts pinyin answers with wrong or missing tones.
+	IgnoreTones bool
+}
+
+// Grade checks a typed answer against a card's term and dictionary entry.
+// The answer may be the term in simplified or traditional characters, its
+// pinyin with tone numbers, tone marks or (when IgnoreTones is set) no tones,
+// or an English keyword from any of the entry's senses.
+func Grade(answer, term string, entry dict.DictionaryEntry, opts Options) Match {
+	answer = strings.TrimSpace(answer)
+	switch {
+	case answer == "":
+		return NoMatch
+	case answer == term || answer == entry.Simplified || answer == entry.Traditional:
+		return TermMatch
+	case matchReading(answer, entry, opts):
+		return ReadingMatch
+	case matchMeaning(answer, entry):
+		return MeaningMatch
+	}
+	return NoMatch
+}
+
+func matchReading(answer string, entry dict.DictionaryEntry, opts Options) bool {
+	readings := []string{entry.Pinyin}
+	for _, sense := range entry.Senses {
+		readings = append(readings, sense.Pinyin)
+	}
+	want := pinyin.Normalize(answer)
+	for _, reading := range readings {
+		if reading == "" {
+			continue
+		}
+		got := pinyin.Normalize(reading)
+		if got == want || opts.IgnoreTones && pinyin.StripTones(got) == pinyin.StripTones(want) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchMeaning(answer string, entry dict.DictionaryEntry) bool {
+	definitions := append([]string{entry.English}, entry.Glosses...)
+	for _, sense := range entry.Senses {
+		definitions = append(definitions, sense.English)
+	}
+	want := keyword(answer)
+	for _, definition := range definitions {
+		for _, part := range strings.Split(definition, ";") {
+			if got := keyword(part); got != "" && got == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keyword reduces an English gloss such as "to study (a subject)" to the
+// words a user would type for it: "study".
+func keyword(gloss string) string {
+	var b strings.Builder
+	depth := 0
+	for _, r := range strings.ToLower(gloss) {
+		switch {
+		case r == '(' || r == '[':
+			depth++
+		case r == ')' || r == ']':
+			if depth > 0 {
+				depth--
+			}
+		case depth == 0:
+			b.WriteRune(r)
+		}
+	}
+	words := strings.Fields(strings.Trim(b.String(), " .!?"))
+	for len(words) > 1 && (words[0] == "to" || words[0] == "a" || words[0] == "an" || words[0] == "the") {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
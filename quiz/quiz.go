@@ -0,0 +1,15 @@
+package quiz
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Blank replaces every occurrence of term in sentence with one ＿ per
+// character.
+func Blank(sentence, term string) string {
+	if term == "" {
+		return sentence
+	}
+	return strings.ReplaceAll(sentence, term, strings.Repeat("＿", utf8.RuneCountInString(term)))
+}
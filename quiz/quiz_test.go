@@ -0,0 +1,43 @@
+package quiz
+
+import (
+	"testing"
+
+	"github.com/flashcards/dict"
+)
+
+func TestBlank(t *testing.T) {
+	if got := Blank("我是学生，他也是学生。", "学生"); got != "我是＿＿，他也是＿＿。" {
+		t.Errorf("Got %q", got)
+	}
+}
+
+func TestGrade(t *testing.T) {
+	entry := dict.DictionaryEntry{
+		Traditional: "學習",
+		Simplified:  "学习",
+		Pinyin:      "xue2 xi2",
+		English:     "to learn",
+		Glosses:     []string{"to learn", "to study (a subject)", "learning"},
+	}
+	tests := map[string]Match{
+		"学习":                 TermMatch,
+		"學習":                 TermMatch,
+		"xue2xi2":            ReadingMatch,
+		"xuéxí":              ReadingMatch,
+		"xue xi":             NoMatch,
+		"study":              MeaningMatch,
+		"To Learn":           MeaningMatch,
+		"learning":           MeaningMatch,
+		"subject":            NoMatch,
+		"to study a subject": NoMatch,
+	}
+	for answer, want := range tests {
+		if got := Grade(answer, "学习", entry, Options{}); got != want {
+			t.Errorf("Grade(%q) = %v; wanted %v", answer, got, want)
+		}
+	}
+	if got := Grade("xue xi", "学习", entry, Options{IgnoreTones: true}); got != ReadingMatch {
+		t.Errorf("Grade ignoring tones = %v; wanted %v", got, ReadingMatch)
+	}
+}
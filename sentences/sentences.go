@@ -0,0 +1,150 @@
+package sentences
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Sentence struct {
+	Text        string
+	Translation string
+}
+
+// Index finds example sentences containing a term.
+type Index struct {
+	sentences []Sentence
+	// byRune lists the sentences containing each rune, to narrow down the
+	// sentences to search for a term.
+	byRune map[rune][]int
+}
+
+// LoadTSV reads sentence pairs, one per line, either as
+// sentence<TAB>translation or in the Tatoeba export layout
+// id<TAB>sentence<TAB>id<TAB>translation.
+func LoadTSV(filepath string) (*Index, error) {
+	file, err := os.Open(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	index := &Index{byRune: make(map[rune][]int)}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		var sentence Sentence
+		switch len(fields) {
+		case 2:
+			sentence = Sentence{Text: fields[0], Translation: fields[1]}
+		case 4:
+			sentence = Sentence{Text: fields[1], Translation: fields[3]}
+		default:
+			continue
+		}
+		index.add(sentence)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("LoadTSV %q: %v", filepath, err)
+	}
+	return index, nil
+}
+
+func (ix *Index) add(sentence Sentence) {
+	i := len(ix.sentences)
+	ix.sentences = append(ix.sentences, sentence)
+	seen := make(map[rune]bool)
+	for _, r := range sentence.Text {
+		if !seen[r] {
+			seen[r] = true
+			ix.byRune[r] = append(ix.byRune[r], i)
+		}
+	}
+}
+
+func (ix *Index) Len() int {
+	return len(ix.sentences)
+}
+
+// unknownRunes counts the letters of text not covered by term or a known
+// word, matching the longest known word at each position.
+func unknownRunes(text, term string, known map[string]bool, longest int) int {
+	runes := []rune(text)
+	termRunes := utf8.RuneCountInString(term)
+	unknown := 0
+	for i := 0; i < len(runes); {
+		if strings.HasPrefix(string(runes[i:]), term) {
+			i += termRunes
+			continue
+		}
+		matched := 0
+		for n := min(longest, len(runes)-i); n > 0; n-- {
+			if known[string(runes[i:i+n])] {
+				matched = n
+				break
+			}
+		}
+		if matched > 0 {
+			i += matched
+			continue
+		}
+		if unicode.IsLetter(runes[i]) {
+			unknown++
+		}
+		i++
+	}
+	return unknown
+}
+
+// Examples returns up to n sentences containing term. Sentences with the
+// fewest words outside known come first, and among those the shortest.
+func (ix *Index) Examples(term string, n int, known map[string]bool) []Sentence {
+	if ix == nil || term == "" || n <= 0 {
+		return nil
+	}
+	var candidates []int
+	for i, r := range term {
+		if i == 0 || len(ix.byRune[r]) < len(candidates) {
+			candidates = ix.byRune[r]
+		}
+	}
+
+	longest := 0
+	for word := range known {
+		longest = max(longest, utf8.RuneCountInString(word))
+	}
+
+	type scored struct {
+		sentence Sentence
+		unknown  int
+		length   int
+	}
+	var matches []scored
+	for _, i := range candidates {
+		sentence := ix.sentences[i]
+		if !strings.Contains(sentence.Text, term) {
+			continue
+		}
+		matches = append(matches, scored{
+			sentence: sentence,
+			unknown:  unknownRunes(sentence.Text, term, known, longest),
+			length:   utf8.RuneCountInString(sentence.Text),
+		})
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].unknown != matches[j].unknown {
+			return matches[i].unknown < matches[j].unknown
+		}
+		return matches[i].length < matches[j].length
+	})
+
+	var examples []Sentence
+	for _, m := range matches[:min(n, len(matches))] {
+		examples = append(examples, m.sentence)
+	}
+	return examples
+}
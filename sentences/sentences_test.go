@@ -0,0 +1,40 @@
+package sentences
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExamples(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sentences.tsv")
+	data := "1\t我喜欢学习中文。\t2\tI like studying Chinese.\n" +
+		"我们一起学习吧。\tLet's study together.\n" +
+		"他在图书馆学习经济学。\tHe studies economics in the library.\n" +
+		"我很好。\tI'm fine.\n" +
+		"malformed line\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	index, err := LoadTSV(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 4 {
+		t.Fatalf("Got %d sentences; wanted 4", index.Len())
+	}
+
+	known := map[string]bool{"我": true, "喜欢": true, "中文": true}
+	got := index.Examples("学习", 2, known)
+	want := []Sentence{
+		{Text: "我喜欢学习中文。", Translation: "I like studying Chinese."},
+		{Text: "我们一起学习吧。", Translation: "Let's study together."},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v; wanted %v", got, want)
+	}
+	if got := index.Examples("学习", 3, nil); got[2].Text != "他在图书馆学习经济学。" {
+		t.Errorf("Got %v; wanted the longest sentence last without known words", got)
+	}
+}
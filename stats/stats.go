@@ -0,0 +1,216 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// Kind is what happened to a card.
+type Kind string
+
+const (
+	Added    Kind = "added"
+	Reviewed Kind = "reviewed"
+)
+
+// Event is one recorded event. Correct is only meaningful for reviews.
+type Event struct {
+	TermId  int64
+	Term    string
+	Kind    Kind
+	Correct bool
+	At      time.Time
+}
+
+type DayCount struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+// Retention is the share of correct reviews of cards within an age range,
+// the age being the time since the card was added.
+type Retention struct {
+	Age     string  `json:"age"`
+	Reviews int     `json:"reviews"`
+	Correct int     `json:"correct"`
+	Rate    float64 `json:"rate"`
+}
+
+type HardCard struct {
+	TermId  int64   `json:"termId"`
+	Term    string  `json:"term"`
+	Reviews int     `json:"reviews"`
+	Wrong   int     `json:"wrong"`
+	Rate    float64 `json:"rate"`
+}
+
+// Report summarizes learning progress.
+type Report struct {
+	AddedPerDay   []DayCount  `json:"addedPerDay"`
+	ReviewsPerDay []DayCount  `json:"reviewsPerDay"`
+	Retention     []Retention `json:"retention"`
+	// Streak is the number of consecutive days with reviews, ending today,
+	// or yesterday if there have been no reviews yet today.
+	Streak  int        `json:"streak"`
+	Hardest []HardCard `json:"hardest"`
+}
+
+// ageBuckets are the upper bounds of the card ages retention is reported for.
+var ageBuckets = []struct {
+	label string
+	max   time.Duration
+}{
+	{"under 1 day", 24 * time.Hour},
+	{"1-7 days", 7 * 24 * time.Hour},
+	{"1-4 weeks", 28 * 24 * time.Hour},
+	{"over 4 weeks", 1<<63 - 1},
+}
+
+// HardestCount is how many cards Report.Hardest lists.
+const HardestCount = 10
+
+const dayFormat = "2006-01-02"
+
+// Compute builds a report from events. now decides the current streak.
+func Compute(events []Event, now time.Time) Report {
+	var report Report
+	added := make(map[string]int)
+	reviews := make(map[string]int)
+	addedAt := make(map[int64]time.Time)
+	for _, e := range events {
+		if e.Kind == Added {
+			added[e.At.Format(dayFormat)]++
+			addedAt[e.TermId] = e.At
+		}
+	}
+
+	retention := make([]Retention, len(ageBuckets))
+	for i, bucket := range ageBuckets {
+		retention[i].Age = bucket.label
+	}
+	cards := make(map[int64]*HardCard)
+	for _, e := range events {
+		if e.Kind != Reviewed {
+			continue
+		}
+		reviews[e.At.Format(dayFormat)]++
+
+		if at, ok := addedAt[e.TermId]; ok {
+			age := e.At.Sub(at)
+			for i, bucket := range ageBuckets {
+				if age < bucket.max {
+					retention[i].Reviews++
+					if e.Correct {
+						retention[i].Correct++
+					}
+					break
+				}
+			}
+		}
+
+		card, ok := cards[e.TermId]
+		if !ok {
+			card = &HardCard{TermId: e.TermId, Term: e.Term}
+			cards[e.TermId] = card
+		}
+		card.Reviews++
+		if !e.Correct {
+			card.Wrong++
+		}
+	}
+	for i := range retention {
+		if retention[i].Reviews > 0 {
+			retention[i].Rate = float64(retention[i].Correct) / float64(retention[i].Reviews)
+		}
+	}
+
+	report.AddedPerDay = perDay(added)
+	report.ReviewsPerDay = perDay(reviews)
+	report.Retention = retention
+	report.Streak = streak(reviews, now)
+	report.Hardest = hardest(cards)
+	return report
+}
+
+func perDay(counts map[string]int) []DayCount {
+	days := make([]DayCount, 0, len(counts))
+	for day, count := range counts {
+		days = append(days, DayCount{Day: day, Count: count})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day < days[j].Day })
+	return days
+}
+
+func streak(reviews map[string]int, now time.Time) int {
+	day := now
+	if reviews[day.Format(dayFormat)] == 0 {
+		day = day.AddDate(0, 0, -1)
+	}
+	n := 0
+	for reviews[day.Format(dayFormat)] > 0 {
+		n++
+		day = day.AddDate(0, 0, -1)
+	}
+	return n
+}
+
+// hardest returns the cards answered wrong most often, breaking ties by the
+// lower success rate.
+func hardest(cards map[int64]*HardCard) []HardCard {
+	var list []HardCard
+	for _, card := range cards {
+		if card.Wrong == 0 {
+			continue
+		}
+		card.Rate = float64(card.Reviews-card.Wrong) / float64(card.Reviews)
+		list = append(list, *card)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Wrong != list[j].Wrong {
+			return list[i].Wrong > list[j].Wrong
+		}
+		if list[i].Rate != list[j].Rate {
+			return list[i].Rate < list[j].Rate
+		}
+		return list[i].TermId < list[j].TermId
+	})
+	if len(list) > HardestCount {
+		list = list[:HardestCount]
+	}
+	return list
+}
+
+// WriteText writes the report in a human readable form.
+func (r Report) WriteText(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+
+	printf("Cards added per day:\n")
+	for _, day := range r.AddedPerDay {
+		printf("  %s %d\n", day.Day, day.Count)
+	}
+	printf("Reviews per day:\n")
+	for _, day := range r.ReviewsPerDay {
+		printf("  %s %d\n", day.Day, day.Count)
+	}
+	printf("Retention by card age:\n")
+	for _, retention := range r.Retention {
+		if retention.Reviews == 0 {
+			printf("  %-12s no reviews\n", retention.Age)
+			continue
+		}
+		printf("  %-12s %3.0f%% of %d\n", retention.Age, retention.Rate*100, retention.Reviews)
+	}
+	printf("Current streak: %d days\n", r.Streak)
+	printf("Hardest cards:\n")
+	for _, card := range r.Hardest {
+		printf("  %s wrong %d of %d\n", card.Term, card.Wrong, card.Reviews)
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+package stats
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompute(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 12, 0, 0, 0, time.UTC) }
+	events := []Event{
+		{TermId: 1, Term: "我", Kind: Added, At: day(1)},
+		{TermId: 2, Term: "学习", Kind: Added, At: day(1)},
+		{TermId: 1, Term: "我", Kind: Reviewed, Correct: true, At: day(1).Add(time.Hour)},
+		{TermId: 2, Term: "学习", Kind: Reviewed, Correct: false, At: day(3)},
+		{TermId: 2, Term: "学习", Kind: Reviewed, Correct: false, At: day(4)},
+		{TermId: 1, Term: "我", Kind: Reviewed, Correct: false, At: day(4)},
+		{TermId: 2, Term: "学习", Kind: Reviewed, Correct: true, At: day(5)},
+	}
+	report := Compute(events, day(6))
+
+	if want := []DayCount{{"2024-03-01", 2}}; !reflect.DeepEqual(report.AddedPerDay, want) {
+		t.Errorf("Got added %v; wanted %v", report.AddedPerDay, want)
+	}
+	wantReviews := []DayCount{{"2024-03-01", 1}, {"2024-03-03", 1}, {"2024-03-04", 2}, {"2024-03-05", 1}}
+	if !reflect.DeepEqual(report.ReviewsPerDay, wantReviews) {
+		t.Errorf("Got reviews %v; wanted %v", report.ReviewsPerDay, wantReviews)
+	}
+	if got := report.Retention[0]; got.Reviews != 1 || got.Rate != 1 {
+		t.Errorf("Got retention under a day %+v; wanted 1 of 1", got)
+	}
+	if got := report.Retention[1]; got.Reviews != 4 || got.Correct != 1 {
+		t.Errorf("Got retention for 1-7 days %+v; wanted 1 of 4", got)
+	}
+	if report.Streak != 3 {
+		t.Errorf("Got streak %d; wanted 3", report.Streak)
+	}
+	if len(report.Hardest) != 2 || report.Hardest[0].Term != "学习" || report.Hardest[0].Wrong != 2 {
+		t.Errorf("Got hardest %+v; wanted 学习 first", report.Hardest)
+	}
+
+	var b strings.Builder
+	if err := report.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "Current streak: 3 days") {
+		t.Errorf("Text report is missing the streak:\n%s", b.String())
+	}
+}
@@ -0,0 +1,57 @@
+package strokes
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Character is the stroke data of one character from the Make Me a Hanzi
+// graphics.txt. Strokes are SVG paths and Medians the points along the
+// middle of each stroke, in the order they are written. Coordinates are on a
+// 1024 unit grid with the y axis pointing up and the baseline at 900.
+type Character struct {
+	Character string         `json:"character"`
+	Strokes   []string       `json:"strokes"`
+	Medians   [][][2]float64 `json:"medians"`
+}
+
+// Load reads the stroke data for chars from graphics.txt. Characters without
+// data are left out of the result.
+func Load(graphicsPath string, chars []string) (map[string]Character, error) {
+	file, err := os.Open(graphicsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// graphics.txt is large, so only lines for the wanted characters are
+	// decoded.
+	prefixes := make(map[string][]byte, len(chars))
+	for _, c := range chars {
+		prefixes[c] = []byte(fmt.Sprintf(`{"character":"%s"`, c))
+	}
+
+	found := make(map[string]Character)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() && len(found) < len(prefixes) {
+		line := scanner.Bytes()
+		for c, prefix := range prefixes {
+			if !bytes.HasPrefix(line, prefix) {
+				continue
+			}
+			var character Character
+			if err := json.Unmarshal(line, &character); err != nil {
+				return nil, fmt.Errorf("strokes.Load %q: %v", c, err)
+			}
+			found[c] = character
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("strokes.Load: %v", err)
+	}
+	return found, nil
+}
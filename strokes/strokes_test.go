@@ -0,0 +1,43 @@
+package strokes
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graphics.txt")
+	data := `{"character":"一","strokes":["M 100 400 L 900 400 L 900 380 Z"],"medians":[[[100,390],[900,390]]]}
+{"character":"二","strokes":["M 200 600 L 800 600 Z","M 100 200 L 900 200 Z"],"medians":[[[200,600],[800,600]],[[100,200],[900,200]]]}
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	chars, err := Load(path, []string{"二", "三"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chars) != 1 {
+		t.Fatalf("Got %d characters; wanted only 二", len(chars))
+	}
+
+	two := chars["二"]
+	for name, svg := range map[string]string{"numbered": two.NumberedSVG(), "frames": two.FramesSVG(1)} {
+		if err := xml.Unmarshal([]byte(svg), new(struct{})); err != nil {
+			t.Errorf("%s SVG is not well formed: %v", name, err)
+		}
+	}
+	if got := strings.Count(two.NumberedSVG(), "<text"); got != 2 {
+		t.Errorf("Got %d stroke numbers; wanted 2", got)
+	}
+	frames := two.FramesSVG(1)
+	if got := strings.Count(frames, `fill="#c00"`); got != 2 {
+		t.Errorf("Got %d highlighted strokes; wanted one per frame", got)
+	}
+	if !strings.Contains(frames, `viewBox="0 0 1024 2048"`) {
+		t.Errorf("Frames not laid out one per row: %s", frames[:80])
+	}
+}
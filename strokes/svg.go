@@ -0,0 +1,102 @@
+package strokes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	size      = 1024
+	drawn     = "#333"
+	current   = "#c00"
+	upcoming  = "#ddd"
+	guideLine = "#ccc"
+)
+
+// flip maps Make Me a Hanzi coordinates to SVG coordinates.
+const flip = `transform="scale(1, -1) translate(0, -900)"`
+
+func writeGuides(b *strings.Builder) {
+	fmt.Fprintf(b, `<g stroke="%s" stroke-width="4" stroke-dasharray="16,16">`, guideLine)
+	fmt.Fprintf(b, `<line x1="0" y1="0" x2="%d" y2="%d"/><line x1="%d" y1="0" x2="0" y2="%d"/>`, size, size, size, size)
+	fmt.Fprintf(b, `<line x1="%d" y1="0" x2="%d" y2="%d"/><line x1="0" y1="%d" x2="%d" y2="%d"/>`, size/2, size/2, size, size/2, size, size/2)
+	fmt.Fprintf(b, `<rect x="0" y="0" width="%d" height="%d" fill="none" stroke-dasharray="none"/></g>`, size, size)
+}
+
+// NumberedSVG draws the whole character with each stroke numbered at the
+// point where it starts.
+func (c Character) NumberedSVG() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="256" height="256">`, size, size)
+	writeGuides(&b)
+	fmt.Fprintf(&b, `<g %s>`, flip)
+	for _, stroke := range c.Strokes {
+		fmt.Fprintf(&b, `<path d="%s" fill="%s"/>`, stroke, drawn)
+	}
+	b.WriteString(`</g>`)
+	for i, median := range c.Medians {
+		if len(median) == 0 {
+			continue
+		}
+		x, y := median[0][0], 900-median[0][1]
+		fmt.Fprintf(&b, `<circle cx="%.0f" cy="%.0f" r="40" fill="%s"/>`, x, y, current)
+		fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" font-size="56" font-family="sans-serif" fill="#fff" text-anchor="middle" dominant-baseline="central">%d</text>`, x, y, i+1)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// FramesSVG draws one frame per stroke, perRow frames to a row. Each frame
+// shows the strokes written so far, the new stroke highlighted and the
+// strokes still to come in light grey.
+func (c Character) FramesSVG(perRow int) string {
+	if perRow < 1 {
+		perRow = 1
+	}
+	n := len(c.Strokes)
+	cols := min(n, perRow)
+	rows := (n + perRow - 1) / perRow
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d">`,
+		cols*size, rows*size, cols*128, rows*128)
+	for frame := 0; frame < n; frame++ {
+		fmt.Fprintf(&b, `<g transform="translate(%d, %d)">`, frame%perRow*size, frame/perRow*size)
+		writeGuides(&b)
+		fmt.Fprintf(&b, `<g %s>`, flip)
+		for i, stroke := range c.Strokes {
+			color := drawn
+			if i == frame {
+				color = current
+			} else if i > frame {
+				color = upcoming
+			}
+			fmt.Fprintf(&b, `<path d="%s" fill="%s"/>`, stroke, color)
+		}
+		b.WriteString(`</g></g>`)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// WriteFiles writes <char>-numbered.svg and <char>-frames.svg to dir and
+// returns their paths.
+func (c Character) WriteFiles(dir string, perRow int) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	files := map[string]string{
+		filepath.Join(dir, c.Character+"-numbered.svg"): c.NumberedSVG(),
+		filepath.Join(dir, c.Character+"-frames.svg"):   c.FramesSVG(perRow),
+	}
+	var paths []string
+	for path, svg := range files {
+		if err := os.WriteFile(path, []byte(svg), 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
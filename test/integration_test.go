@@ -1,23 +1,40 @@
 package test
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
 	"reflect"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/docker/go-connections/nat"
+	"github.com/flashcards/api"
+	"github.com/flashcards/backup"
 	"github.com/flashcards/database"
 	"github.com/flashcards/dbinterface"
 	"github.com/flashcards/dict"
+	"github.com/flashcards/language"
+	"github.com/flashcards/sentences"
 	"github.com/go-sql-driver/mysql"
 	testcontainers "github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/wait"
 )
 
-var dbc *dbinterface.DatabaseConn
+var (
+	dbc *dbinterface.DatabaseConn
+	cfg mysql.Config
+)
 
 func createDbContainer(ctx context.Context, databaseName string) (testcontainers.Container, string, error) {
 	port := "3306"
@@ -68,14 +85,14 @@ func TestMain(m *testing.M) {
 	}
 
 	addr := fmt.Sprintf("127.0.0.1:%s", port)
-	cfg := mysql.Config{
+	cfg = mysql.Config{
 		User:   databaseName,
 		Passwd: "secret",
 		Net:    "tcp",
 		Addr:   addr,
 		DBName: databaseName,
 	}
-	dbc, err = dbinterface.Connect(cfg, tableName)
+	dbc, err = dbinterface.Connect(ctx, cfg, tableName)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -89,7 +106,7 @@ func TestMain(m *testing.M) {
 		},
 	}
 
-	_, err = dbinterface.Add(dbc, "我", dictMap)
+	_, err = dbinterface.Add(ctx, dbc, "我", dictMap)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -100,15 +117,18 @@ func TestMain(m *testing.M) {
 }
 
 func TestAdd(t *testing.T) {
+	ctx := context.Background()
 	type args struct {
+		name      string
 		termToAdd string
 		dictMap   dict.DictMap
-		wantResp  []int64
+		wantAdded bool
 		wantErr   any
 		cleanup   func(string)
 	}
-	tests := map[string]args{
-		"success": {
+	tests := []args{
+		{
+			name:      "success",
 			termToAdd: "你",
 			dictMap: dict.DictMap{
 				"你": dict.DictionaryEntry{
@@ -118,36 +138,50 @@ func TestAdd(t *testing.T) {
 					English:     "you",
 				},
 			},
-			wantResp: []int64{2},
-			wantErr:  nil,
+			wantAdded: true,
+			wantErr:   nil,
 			cleanup: func(termToDelete string) {
-				err := dbinterface.Delete(dbc, termToDelete)
+				err := dbinterface.Delete(ctx, dbc, termToDelete)
 				if err != nil {
 					t.Fatalf("Error when doing cleanup and deleting %s", termToDelete)
 				}
 			},
 		},
-		"adding duplicate": {
+		{
+			name:      "adding duplicate",
 			termToAdd: "我",
-			wantResp:  nil,
 			wantErr:   nil,
 		},
-		"unexpected language": {
+		{
+			name:      "unexpected language",
 			termToAdd: "c",
-			wantResp:  nil,
 			wantErr:   dbinterface.ErrUnexpectedLanguage{},
 		},
 	}
-	for name, test := range tests {
-		t.Run(name, func(t *testing.T) {
-			got, err := dbinterface.Add(dbc, test.termToAdd, test.dictMap)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := dbinterface.Add(ctx, dbc, test.termToAdd, test.dictMap)
 			if test.wantErr != nil && !errors.As(err, &test.wantErr) {
 				t.Errorf("Got error %v, wanted %v", err, test.wantErr)
 			} else if test.wantErr == nil && err != nil {
 				t.Errorf("Got error, wanted nil")
 			}
-			if !reflect.DeepEqual(got, test.wantResp) {
-				t.Errorf("Got %v; wanted %v", got, test.wantResp)
+			// Duplicates use up auto-increment ids, so the id added is
+			// looked up rather than predicted.
+			var want []int64
+			if test.wantAdded {
+				found, err := dbinterface.Find(ctx, dbc, test.termToAdd)
+				if err != nil {
+					t.Fatalf("Error when finding %s: %v", test.termToAdd, err)
+				}
+				for id, termDef := range found {
+					if termDef.Term == test.termToAdd {
+						want = append(want, id)
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Got %v; wanted %v", got, want)
 			}
 			if test.cleanup != nil {
 				test.cleanup(test.termToAdd)
@@ -157,6 +191,7 @@ func TestAdd(t *testing.T) {
 }
 
 func TestDelete(t *testing.T) {
+	ctx := context.Background()
 	type args struct {
 		setup        func(string)
 		termToDelete string
@@ -174,7 +209,7 @@ func TestDelete(t *testing.T) {
 						English:     "you",
 					},
 				}
-				_, err := dbinterface.Add(dbc, termToAdd, dictMap)
+				_, err := dbinterface.Add(ctx, dbc, termToAdd, dictMap)
 				if err != nil {
 					t.Fatalf("Error when adding term %s", termToAdd)
 				}
@@ -191,7 +226,7 @@ func TestDelete(t *testing.T) {
 			if test.setup != nil {
 				test.setup(test.termToDelete)
 			}
-			err := dbinterface.Delete(dbc, test.termToDelete)
+			err := dbinterface.Delete(ctx, dbc, test.termToDelete)
 			if test.wantErr != nil && !errors.As(err, &test.wantErr) {
 				t.Errorf("Got error %v, wanted %v", err, test.wantErr)
 			} else if test.wantErr == nil && err != nil {
@@ -202,18 +237,19 @@ func TestDelete(t *testing.T) {
 }
 
 func TestFind(t *testing.T) {
+	ctx := context.Background()
 	type args struct {
 		setup      func()
 		termToFind string
-		wantResp   map[int64]dbinterface.TermDef
+		wantResp   []dbinterface.TermDef
 		wantErr    any
 		cleanup    func()
 	}
 	tests := map[string]args{
 		"find term": {
 			termToFind: "我",
-			wantResp: map[int64]dbinterface.TermDef{
-				1: {Term: "我", Definition: "me"},
+			wantResp: []dbinterface.TermDef{
+				{Term: "我", Definition: "me"},
 			},
 			wantErr: nil,
 		},
@@ -231,23 +267,23 @@ func TestFind(t *testing.T) {
 						English:     "us, we",
 					},
 				}
-				_, err := dbinterface.Add(dbc, "我们", dictMap)
+				_, err := dbinterface.Add(ctx, dbc, "我们", dictMap)
 				if err != nil {
 					t.Fatalf("Error when adding term %s", "我们")
 				}
 			},
 			termToFind: "我",
-			wantResp: map[int64]dbinterface.TermDef{
-				1: {Term: "我", Definition: "me"},
-				3: {Term: "我们", Definition: "us, we"},
+			wantResp: []dbinterface.TermDef{
+				{Term: "我", Definition: "me"},
+				{Term: "我们", Definition: "us, we"},
 			},
 			wantErr: nil,
 			cleanup: func() {
-				err := dbinterface.Delete(dbc, "我们")
+				err := dbinterface.Delete(ctx, dbc, "我们")
 				if err != nil {
 					t.Fatalf("Error when deleting term %s", "我们")
 				}
-				err = dbinterface.Delete(dbc, "们")
+				err = dbinterface.Delete(ctx, dbc, "们")
 				if err != nil {
 					t.Fatalf("Error when deleting term %s", "们")
 				}
@@ -259,13 +295,15 @@ func TestFind(t *testing.T) {
 			if test.setup != nil {
 				test.setup()
 			}
-			got, err := dbinterface.Find(dbc, test.termToFind)
+			found, err := dbinterface.Find(ctx, dbc, test.termToFind)
 			if test.wantErr != nil && !errors.As(err, &test.wantErr) {
 				t.Errorf("Got error %v, wanted %v", err, test.wantErr)
 			} else if test.wantErr == nil && err != nil {
 				t.Errorf("Got error, wanted nil")
 			}
-			if !reflect.DeepEqual(got, test.wantResp) {
+			// Ids are not reused, so they depend on what earlier tests
+			// added and deleted. Only the terms are compared.
+			if got := sortedTerms(found); !reflect.DeepEqual(got, test.wantResp) {
 				t.Errorf("Got %v; wanted %v", got, test.wantResp)
 			}
 			if test.cleanup != nil {
@@ -275,7 +313,18 @@ func TestFind(t *testing.T) {
 	}
 }
 
+// sortedTerms returns the terms of a Find or List result ordered by term.
+func sortedTerms(terms map[int64]dbinterface.TermDef) []dbinterface.TermDef {
+	var sorted []dbinterface.TermDef
+	for _, termDef := range terms {
+		sorted = append(sorted, termDef)
+	}
+	slices.SortFunc(sorted, func(a, b dbinterface.TermDef) int { return strings.Compare(a.Term, b.Term) })
+	return sorted
+}
+
 func TestList(t *testing.T) {
+	ctx := context.Background()
 	type args struct {
 		wantResp map[int64]dbinterface.TermDef
 		wantErr  any
@@ -290,7 +339,130 @@ func TestList(t *testing.T) {
 	}
 	for name, test := range tests {
 		t.Run(name, func(t *testing.T) {
-			got, err := dbinterface.List(dbc)
+			got, err := dbinterface.List(ctx, dbc)
+			if test.wantErr != nil && !errors.As(err, &test.wantErr) {
+				t.Errorf("Got error %v, wanted %v", err, test.wantErr)
+			} else if test.wantErr == nil && err != nil {
+				t.Errorf("Got error, wanted nil")
+			}
+			if !reflect.DeepEqual(got, test.wantResp) {
+				t.Errorf("Got %v; wanted %v", got, test.wantResp)
+			}
+		})
+	}
+}
+
+func TestQuotedTerm(t *testing.T) {
+	ctx := context.Background()
+	// A term is a value, not part of the query, so quotes in it match
+	// nothing rather than every term.
+	tags, err := dbinterface.Tags(ctx, dbc, "x' OR term <> '")
+	if !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got %v, %v, wanted not found", tags, err)
+	}
+}
+
+func TestLanguages(t *testing.T) {
+	ctx := context.Background()
+	ja, err := dbinterface.ConnectWithLanguage(ctx, cfg, "term", language.Japanese{})
+	if err != nil {
+		t.Fatalf("Error when connecting: %v", err)
+	}
+
+	// 我 is a Chinese card. Japanese cards are apart from it, so the
+	// Japanese connection does not find it and adds a card of its own.
+	if got, err := dbinterface.Find(ctx, ja, "我"); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got %v, %v, wanted no Japanese 我", got, err)
+	}
+	for _, term := range []string{"我", "ひと"} {
+		ids, err := dbinterface.Add(ctx, ja, term, dict.DictMap{})
+		if err != nil || len(ids) != 1 || ids[0] == 1 {
+			t.Fatalf("Got %v, %v, wanted a new Japanese card for %s", ids, err, term)
+		}
+		t.Cleanup(func() {
+			if err := dbinterface.Delete(ctx, ja, term); err != nil {
+				t.Errorf("Error when deleting: %v", err)
+			}
+			if err := dbinterface.Purge(ctx, ja, term); err != nil {
+				t.Errorf("Error when purging: %v", err)
+			}
+		})
+	}
+	got, err := dbinterface.Find(ctx, dbc, "我")
+	if err != nil {
+		t.Fatalf("Error when finding: %v", err)
+	}
+	for id, termDef := range got {
+		if termDef.Term == "我" && id != 1 {
+			t.Errorf("Got %v, wanted only the Chinese 我", got)
+		}
+	}
+	if got, err := dbinterface.Find(ctx, ja, "ひと"); err != nil || len(got) != 1 {
+		t.Errorf("Got %v, %v, wanted the Japanese card", got, err)
+	}
+	if _, err := dbinterface.Find(ctx, dbc, "ひと"); !errors.As(err, new(*dbinterface.ErrUnexpectedLanguage)) {
+		t.Errorf("Got error %v, wanted kana rejected for Chinese", err)
+	}
+}
+
+func TestDecksAndTags(t *testing.T) {
+	ctx := context.Background()
+	if _, err := dbinterface.CreateDeck(ctx, dbc, "HSK1"); err != nil {
+		t.Fatalf("Error when creating deck: %v", err)
+	}
+	if _, err := dbinterface.CreateDeck(ctx, dbc, "work"); err != nil {
+		t.Fatalf("Error when creating deck: %v", err)
+	}
+	if err := dbinterface.RenameDeck(ctx, dbc, "work", "office"); err != nil {
+		t.Fatalf("Error when renaming deck: %v", err)
+	}
+	if err := dbinterface.AddToDeck(ctx, dbc, "HSK1", "我"); err != nil {
+		t.Fatalf("Error when adding to deck: %v", err)
+	}
+	if err := dbinterface.Tag(ctx, dbc, "我", "pronoun"); err != nil {
+		t.Fatalf("Error when tagging: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := dbinterface.Untag(ctx, dbc, "我", "pronoun"); err != nil {
+			t.Fatalf("Error when untagging: %v", err)
+		}
+		for _, deck := range []string{"HSK1", "office"} {
+			if err := dbinterface.DeleteDeck(ctx, dbc, deck); err != nil {
+				t.Fatalf("Error when deleting deck %s: %v", deck, err)
+			}
+		}
+	})
+
+	type args struct {
+		filter   dbinterface.Filter
+		wantResp map[int64]dbinterface.TermDef
+		wantErr  any
+	}
+	tests := map[string]args{
+		"deck": {
+			filter:   dbinterface.Filter{Deck: "HSK1"},
+			wantResp: map[int64]dbinterface.TermDef{1: {Term: "我", Definition: "me"}},
+		},
+		"renamed empty deck": {
+			filter:   dbinterface.Filter{Deck: "office"},
+			wantResp: map[int64]dbinterface.TermDef{},
+		},
+		"tag expression": {
+			filter:   dbinterface.Filter{Deck: "HSK1", Tags: "pronoun and not (verb or noun)"},
+			wantResp: map[int64]dbinterface.TermDef{1: {Term: "我", Definition: "me"}},
+		},
+		"negated tag": {
+			filter:   dbinterface.Filter{Tags: "not pronoun"},
+			wantResp: map[int64]dbinterface.TermDef{},
+		},
+		"invalid expression": {
+			filter:  dbinterface.Filter{Tags: "pronoun and"},
+			wantErr: dbinterface.ErrInvalidFilter{},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := dbinterface.ListFiltered(ctx, dbc, test.filter)
 			if test.wantErr != nil && !errors.As(err, &test.wantErr) {
 				t.Errorf("Got error %v, wanted %v", err, test.wantErr)
 			} else if test.wantErr == nil && err != nil {
@@ -302,3 +474,1185 @@ func TestList(t *testing.T) {
 		})
 	}
 }
+
+func TestClozes(t *testing.T) {
+	ctx := context.Background()
+	id, err := dbinterface.AddCloze(ctx, dbc, "我", sentences.Sentence{Text: "我是学生。", Translation: "I am a student."})
+	if err != nil {
+		t.Fatalf("Error when adding cloze: %v", err)
+	}
+	if _, err := dbinterface.AddCloze(ctx, dbc, "我", sentences.Sentence{Text: "你好。"}); err == nil {
+		t.Errorf("Added a cloze whose sentence does not contain the term")
+	}
+	if err := dbinterface.RecordClozeReview(ctx, dbc, id, "wo3", true); err != nil {
+		t.Fatalf("Error when recording review: %v", err)
+	}
+
+	clozes, err := dbinterface.ListClozes(ctx, dbc)
+	if err != nil {
+		t.Fatalf("Error when listing clozes: %v", err)
+	}
+	want := dbinterface.Cloze{TermId: 1, Term: "我", Sentence: "我是学生。", Translation: "I am a student."}
+	if got := clozes[id]; got != want {
+		t.Errorf("Got %v, wanted %v", got, want)
+	}
+	reviews, err := dbinterface.ClozeReviews(ctx, dbc, id)
+	if err != nil {
+		t.Fatalf("Error when listing reviews: %v", err)
+	}
+	if len(reviews) != 1 || reviews[0].Answer != "wo3" || !reviews[0].Correct {
+		t.Errorf("Got reviews %v, wanted one correct answer wo3", reviews)
+	}
+
+	// A failed cloze is not a lapse of the term's own card.
+	dbinterface.SetLeechThreshold(dbc, 1)
+	t.Cleanup(func() { dbinterface.SetLeechThreshold(dbc, dbinterface.DefaultLeechThreshold) })
+	if err := dbinterface.RecordClozeReview(ctx, dbc, id, "ni3", false); err != nil {
+		t.Fatalf("Error when recording review: %v", err)
+	}
+	if leeches, err := dbinterface.Leeches(ctx, dbc); err != nil || len(leeches) != 0 {
+		t.Errorf("Got leeches %v, %v, wanted none after a failed cloze", leeches, err)
+	}
+}
+
+func TestAnswers(t *testing.T) {
+	ctx := context.Background()
+	for _, answer := range []string{"wo3", "you"} {
+		if err := dbinterface.RecordAnswer(ctx, dbc, 1, answer, answer == "wo3"); err != nil {
+			t.Fatalf("Error when recording answer: %v", err)
+		}
+	}
+	got, err := dbinterface.Answers(ctx, dbc, 1)
+	if err != nil {
+		t.Fatalf("Error when listing answers: %v", err)
+	}
+	if len(got) != 2 || got[0].Answer != "wo3" || !got[0].Correct || got[1].Answer != "you" || got[1].Correct {
+		t.Errorf("Got answers %v, wanted wo3 (correct) then you (wrong)", got)
+	}
+}
+
+func TestCardDirections(t *testing.T) {
+	ctx := context.Background()
+	if _, err := dbinterface.CreateDeck(ctx, dbc, "reading"); err != nil {
+		t.Fatalf("Error when creating deck: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := dbinterface.DeleteDeck(ctx, dbc, "reading"); err != nil {
+			t.Fatalf("Error when deleting deck: %v", err)
+		}
+	})
+	if err := dbinterface.AddToDeck(ctx, dbc, "reading", "我"); err != nil {
+		t.Fatalf("Error when adding to deck: %v", err)
+	}
+
+	cards, err := dbinterface.Cards(ctx, dbc, dbinterface.Filter{Deck: "reading"})
+	if err != nil {
+		t.Fatalf("Error when listing cards: %v", err)
+	}
+	if len(cards) != len(dbinterface.Directions) {
+		t.Errorf("Got %d cards, wanted one per direction", len(cards))
+	}
+
+	if err := dbinterface.SetDeckDirections(ctx, dbc, "reading", []dbinterface.Direction{dbinterface.HanziToPinyin}); err != nil {
+		t.Fatalf("Error when setting directions: %v", err)
+	}
+	cards, err = dbinterface.Cards(ctx, dbc, dbinterface.Filter{Deck: "reading"})
+	if err != nil {
+		t.Fatalf("Error when listing cards: %v", err)
+	}
+	if len(cards) != 1 || cards[0].Direction != dbinterface.HanziToPinyin {
+		t.Fatalf("Got cards %v, wanted only hanzi-pinyin", cards)
+	}
+
+	if err := dbinterface.RecordGrade(ctx, dbc, cards[0], dbinterface.Right); err != nil {
+		t.Fatalf("Error when recording grade: %v", err)
+	}
+	reading, err := dbinterface.LastGrades(ctx, dbc, dbinterface.HanziToPinyin)
+	if err != nil {
+		t.Fatalf("Error when getting grades: %v", err)
+	}
+	writing, err := dbinterface.LastGrades(ctx, dbc, dbinterface.PinyinToHanzi)
+	if err != nil {
+		t.Fatalf("Error when getting grades: %v", err)
+	}
+	if grade, ok := reading[cards[0].TermId]; !ok || grade != dbinterface.Right {
+		t.Errorf("Got reading grade %v, wanted %v", grade, dbinterface.Right)
+	}
+	if _, ok := writing[cards[0].TermId]; ok {
+		t.Errorf("Reviewing the reading graded the writing direction too")
+	}
+}
+
+func TestStats(t *testing.T) {
+	ctx := context.Background()
+	if err := dbinterface.RecordAnswer(ctx, dbc, 1, "wrong", false); err != nil {
+		t.Fatalf("Error when recording answer: %v", err)
+	}
+	report, err := dbinterface.Stats(ctx, dbc)
+	if err != nil {
+		t.Fatalf("Error when computing stats: %v", err)
+	}
+	if len(report.ReviewsPerDay) == 0 || report.Streak != 1 {
+		t.Errorf("Got reviews %v and streak %d, wanted reviews today", report.ReviewsPerDay, report.Streak)
+	}
+	found := false
+	for _, card := range report.Hardest {
+		found = found || card.TermId == 1
+	}
+	if !found {
+		t.Errorf("Got hardest cards %v, wanted term 1 among them", report.Hardest)
+	}
+}
+
+func TestLeeches(t *testing.T) {
+	ctx := context.Background()
+	if err := dbinterface.ResetLeech(ctx, dbc, "我"); err != nil {
+		t.Fatalf("Error when resetting lapses: %v", err)
+	}
+	dbinterface.SetLeechThreshold(dbc, 2)
+	t.Cleanup(func() {
+		dbinterface.SetLeechThreshold(dbc, dbinterface.DefaultLeechThreshold)
+		if err := dbinterface.ResetLeech(ctx, dbc, "我"); err != nil {
+			t.Fatalf("Error when resetting leech: %v", err)
+		}
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := dbinterface.RecordAnswer(ctx, dbc, 1, "wrong", false); err != nil {
+			t.Fatalf("Error when recording answer: %v", err)
+		}
+	}
+	leeches, err := dbinterface.Leeches(ctx, dbc)
+	if err != nil {
+		t.Fatalf("Error when listing leeches: %v", err)
+	}
+	if leech, ok := leeches[1]; !ok || leech.Lapses != 2 || len(leech.Suggestions) == 0 {
+		t.Errorf("Got leeches %v, wanted 我 with 2 lapses and suggestions", leeches)
+	}
+	cards, err := dbinterface.Cards(ctx, dbc, dbinterface.Filter{})
+	if err != nil {
+		t.Fatalf("Error when listing cards: %v", err)
+	}
+	for _, card := range cards {
+		if card.TermId == 1 {
+			t.Errorf("Leech %s is still quizzed", card.Term)
+		}
+	}
+
+	// Being a leech is not a tag, and a tag of that name is just a tag.
+	tags, err := dbinterface.Tags(ctx, dbc, "我")
+	if err != nil {
+		t.Fatalf("Error when listing tags: %v", err)
+	}
+	for _, tag := range tags {
+		if tag == "leech" {
+			t.Errorf("Got tags %v, wanted no leech tag", tags)
+		}
+	}
+	if err := dbinterface.ResetLeech(ctx, dbc, "我"); err != nil {
+		t.Fatalf("Error when resetting leech: %v", err)
+	}
+	if err := dbinterface.Tag(ctx, dbc, "我", "leech"); err != nil {
+		t.Fatalf("Error when tagging: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := dbinterface.Untag(ctx, dbc, "我", "leech"); err != nil {
+			t.Errorf("Error when untagging: %v", err)
+		}
+	})
+	if leeches, err := dbinterface.Leeches(ctx, dbc); err != nil || len(leeches) != 0 {
+		t.Errorf("Got leeches %v, %v, wanted none for a card tagged leech", leeches, err)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	ctx := context.Background()
+	dictMap := dict.DictMap{"他": dict.DictionaryEntry{Simplified: "他", Pinyin: "ta1", English: "he"}}
+	ids, err := dbinterface.Add(ctx, dbc, "他", dictMap)
+	if err != nil || len(ids) != 1 {
+		t.Fatalf("Error when adding term: %v", err)
+	}
+	if err := dbinterface.Tag(ctx, dbc, "他", "pronoun"); err != nil {
+		t.Fatalf("Error when tagging: %v", err)
+	}
+	if err := dbinterface.Delete(ctx, dbc, "他"); err != nil {
+		t.Fatalf("Error when deleting: %v", err)
+	}
+
+	if _, err := dbinterface.Find(ctx, dbc, "他"); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got error %v, wanted the trashed term to be hidden", err)
+	}
+	trashed, err := dbinterface.Trash(ctx, dbc)
+	if err != nil {
+		t.Fatalf("Error when listing trash: %v", err)
+	}
+	if trashed[ids[0]].Term != "他" {
+		t.Errorf("Got trash %v, wanted 他 at %d", trashed, ids[0])
+	}
+
+	if err := dbinterface.Restore(ctx, dbc, "他"); err != nil {
+		t.Fatalf("Error when restoring: %v", err)
+	}
+	got, err := dbinterface.ListFiltered(ctx, dbc, dbinterface.Filter{Tags: "pronoun"})
+	if err != nil {
+		t.Fatalf("Error when listing: %v", err)
+	}
+	if _, ok := got[ids[0]]; !ok {
+		t.Errorf("Got %v, wanted 他 restored at %d with its tag", got, ids[0])
+	}
+
+	// Undoing the restore puts 他 back in the trash.
+	entries, err := dbinterface.Undo(ctx, dbc, 1)
+	if err != nil {
+		t.Fatalf("Error when undoing: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Op != dbinterface.JournalRestore {
+		t.Errorf("Got %v, wanted the restore undone", entries)
+	}
+	if trashed, err := dbinterface.Trash(ctx, dbc); err != nil || trashed[ids[0]].Term != "他" {
+		t.Errorf("Got trash %v, %v, wanted 他 back in it", trashed, err)
+	}
+
+	// Once purged, nothing that was done to 他 can be undone or redone.
+	if err := dbinterface.Purge(ctx, dbc, "他"); err != nil {
+		t.Fatalf("Error when purging: %v", err)
+	}
+	if err := dbinterface.Restore(ctx, dbc, "他"); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got error %v, wanted the purged term to be gone", err)
+	}
+	if entries, err := dbinterface.Redo(ctx, dbc, 1); err != nil || len(entries) != 0 {
+		t.Errorf("Got %v, %v, wanted nothing to redo after a purge", entries, err)
+	}
+	// The last operation left is one made before this test, which is redone
+	// so other tests find it.
+	entries, err = dbinterface.Undo(ctx, dbc, 1)
+	if err != nil {
+		t.Fatalf("Error when undoing: %v", err)
+	}
+	for _, entry := range entries {
+		if slices.Contains(entry.Ids, ids[0]) {
+			t.Errorf("Got %v undone, wanted the purged term left out", entry)
+		}
+	}
+	if _, err := dbinterface.Redo(ctx, dbc, len(entries)); err != nil {
+		t.Fatalf("Error when redoing: %v", err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A table as created before soft deletes, with the gap left by a
+	// deleted term.
+	ctx := context.Background()
+	for _, exec := range []string{
+		"CREATE TABLE legacy (id INT AUTO_INCREMENT NOT NULL, term VARCHAR(128) NOT NULL, definition VARCHAR(255) NOT NULL, PRIMARY KEY (id))",
+		"INSERT INTO legacy (id, term, definition) VALUES (1, '我', 'me'), (3, '你', 'you')",
+	} {
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := database.Migrate(ctx, db, "legacy"); err != nil {
+			t.Fatalf("Error when migrating: %v", err)
+		}
+	}
+
+	legacy, err := dbinterface.Connect(ctx, cfg, "legacy")
+	if err != nil {
+		t.Fatalf("Error when connecting: %v", err)
+	}
+	got, err := dbinterface.List(ctx, legacy)
+	if err != nil {
+		t.Fatalf("Error when listing: %v", err)
+	}
+	want := map[int64]dbinterface.TermDef{1: {Term: "我", Definition: "me"}, 3: {Term: "你", Definition: "you"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v, wanted the ids kept as %v", got, want)
+	}
+	ids, err := dbinterface.Add(ctx, legacy, "他", dict.DictMap{})
+	if err != nil {
+		t.Fatalf("Error when adding: %v", err)
+	}
+	if !reflect.DeepEqual(ids, []int64{4}) {
+		t.Errorf("Got ids %v, wanted a new id instead of the gap", ids)
+	}
+}
+
+// TestConcurrentMigrate has clients connecting to new tables at the same
+// time, which must take turns migrating them.
+func TestConcurrentMigrate(t *testing.T) {
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := database.Migrate(ctx, db, "racing"); err != nil {
+				t.Errorf("Error when migrating: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var version int
+	if err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM racing_migrations").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != database.LatestVersion() {
+		t.Errorf("Got version %d, wanted %d", version, database.LatestVersion())
+	}
+}
+
+func TestMigrateUserTerms(t *testing.T) {
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A backup taken with users but marked as version 1 holds 我 once for
+	// each of two users, which are not duplicates.
+	ctx := context.Background()
+	if err := database.CreateAt(ctx, db, "owners", 1); err != nil {
+		t.Fatal(err)
+	}
+	for _, exec := range []string{
+		"INSERT INTO owners_users (id, name) VALUES (1, 'default'), (2, 'bob')",
+		"INSERT INTO owners (id, user_id, term, definition) VALUES (1, 1, '我', 'me'), (2, 2, '我', 'I')",
+	} {
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.Migrate(ctx, db, "owners"); err != nil {
+		t.Fatalf("Error when migrating: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM owners").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Got %d terms, wanted each user's 我 kept", count)
+	}
+}
+
+func TestMigrateLeechTags(t *testing.T) {
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// Before version 7 the app tagged leeches. 我 was tagged by the app, 你
+	// by the user, and both have lapses.
+	ctx := context.Background()
+	if err := database.CreateAt(ctx, db, "leechy", 6); err != nil {
+		t.Fatal(err)
+	}
+	for _, exec := range []string{
+		"INSERT INTO leechy (id, user_id, term, definition) VALUES (1, 1, '我', 'me'), (2, 1, '你', 'you')",
+		"INSERT INTO leechy_users (id, name) VALUES (1, 'default')",
+		"INSERT INTO leechy_tags (id, user_id, name) VALUES (1, 1, 'leech')",
+		"INSERT INTO leechy_term_tags (term_id, tag_id) VALUES (1, 1), (2, 1)",
+		"INSERT INTO leechy_lapses (term_id, lapses) VALUES (1, 8), (2, 3)",
+		"INSERT INTO leechy_history (user_id, term_id, term, kind, detail) VALUES (1, 1, '我', 'tagged', 'leech after 8 lapses'), (1, 2, '你', 'tagged', 'leech')",
+	} {
+		if _, err := db.ExecContext(ctx, exec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := database.Migrate(ctx, db, "leechy"); err != nil {
+		t.Fatalf("Error when migrating: %v", err)
+	}
+
+	leechy, err := dbinterface.Connect(ctx, cfg, "leechy")
+	if err != nil {
+		t.Fatalf("Error when connecting: %v", err)
+	}
+	leeches, err := dbinterface.Leeches(ctx, leechy)
+	if err != nil {
+		t.Fatalf("Error when listing leeches: %v", err)
+	}
+	if _, ok := leeches[1]; !ok || len(leeches) != 1 {
+		t.Errorf("Got leeches %v, wanted only 我", leeches)
+	}
+	for term, want := range map[string][]string{"我": nil, "你": {"leech"}} {
+		tags, err := dbinterface.Tags(ctx, leechy, term)
+		if err != nil {
+			t.Fatalf("Error when listing tags: %v", err)
+		}
+		if !reflect.DeepEqual(tags, want) {
+			t.Errorf("Got tags %v for %s, wanted %v", tags, term, want)
+		}
+	}
+}
+
+// TestConcurrentAddDelete is meant to be run with -race. Many clients adding
+// and deleting the same terms must never leave two copies of a term.
+func TestConcurrentAddDelete(t *testing.T) {
+	ctx := context.Background()
+	terms := []string{"好", "很", "好很"}
+	dictMap := dict.DictMap{
+		"好": {Simplified: "好", Pinyin: "hao3", English: "good"},
+		"很": {Simplified: "很", Pinyin: "hen3", English: "very"},
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				term := terms[(i+j)%len(terms)]
+				if _, err := dbinterface.Add(ctx, dbc, term, dictMap); err != nil {
+					t.Errorf("Error when adding %s: %v", term, err)
+				}
+				if (i+j)%2 == 0 {
+					if err := dbinterface.Delete(ctx, dbc, term); err != nil && !errors.As(err, new(*dbinterface.ErrNotFound)) {
+						t.Errorf("Error when deleting %s: %v", term, err)
+					}
+				}
+				dbinterface.SetLeechThreshold(dbc, dbinterface.DefaultLeechThreshold)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	db, err := sql.Open("mysql", cfg.FormatDSN())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, term := range terms {
+		var copies int
+		if err := db.QueryRow("SELECT COUNT(*) FROM term WHERE term = ?", term).Scan(&copies); err != nil {
+			t.Fatal(err)
+		}
+		if copies != 1 {
+			t.Errorf("Got %d copies of %s, wanted 1", copies, term)
+		}
+	}
+	for _, term := range terms {
+		if err := dbinterface.Delete(ctx, dbc, term); err != nil && !errors.As(err, new(*dbinterface.ErrNotFound)) {
+			t.Fatalf("Error when cleaning up %s: %v", term, err)
+		}
+		if err := dbinterface.Purge(ctx, dbc, term); err != nil {
+			t.Fatalf("Error when cleaning up %s: %v", term, err)
+		}
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	dictMap := dict.DictMap{"她": {Simplified: "她", Pinyin: "ta1", English: "she"}}
+	if _, err := dbinterface.Add(ctx, dbc, "她", dictMap); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Got error %v, wanted %v", err, context.DeadlineExceeded)
+	}
+	if _, err := dbinterface.Find(context.Background(), dbc, "她"); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got error %v, wanted the cancelled add to leave nothing behind", err)
+	}
+}
+
+func TestBatch(t *testing.T) {
+	ctx := context.Background()
+	dictMap := dict.DictMap{"们": {Simplified: "们", Pinyin: "men5", English: "plural marker"}}
+
+	// The whole word is too long for the terms table, so the character added
+	// before it must not stay behind either.
+	if _, err := dbinterface.Add(ctx, dbc, strings.Repeat("爸", 130), dictMap); err == nil {
+		t.Errorf("Added a term longer than the column")
+	}
+	if _, err := dbinterface.Find(ctx, dbc, "爸"); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got error %v, wanted the failed add to be rolled back", err)
+	}
+
+	ops := []dbinterface.BatchOp{
+		{Kind: dbinterface.BatchAdd, Term: "我们"},
+		{Kind: dbinterface.BatchEdit, Term: "我", Definition: "I; me"},
+		{Kind: dbinterface.BatchAdd, Term: "c"},
+		{Kind: dbinterface.BatchDelete, Term: "我"},
+	}
+	results, err := dbinterface.Batch(ctx, dbc, ops, dictMap)
+	if !errors.As(err, new(*dbinterface.ErrUnexpectedLanguage)) {
+		t.Errorf("Got error %v, wanted %v", err, dbinterface.ErrUnexpectedLanguage{})
+	}
+	var statuses []dbinterface.BatchStatus
+	for _, result := range results {
+		statuses = append(statuses, result.Status)
+	}
+	wantStatuses := []dbinterface.BatchStatus{dbinterface.BatchRolledBack, dbinterface.BatchRolledBack, dbinterface.BatchFailed, dbinterface.BatchSkipped}
+	if !reflect.DeepEqual(statuses, wantStatuses) {
+		t.Errorf("Got statuses %v, wanted %v", statuses, wantStatuses)
+	}
+	if _, err := dbinterface.Find(ctx, dbc, "们"); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got error %v, wanted the failed batch to be rolled back", err)
+	}
+
+	results, err = dbinterface.Batch(ctx, dbc, ops[:2], dictMap)
+	if err != nil {
+		t.Fatalf("Error when applying batch: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, term := range []string{"我们", "们"} {
+			if err := dbinterface.Delete(ctx, dbc, term); err != nil {
+				t.Fatalf("Error when deleting %s: %v", term, err)
+			}
+			if err := dbinterface.Purge(ctx, dbc, term); err != nil {
+				t.Fatalf("Error when purging %s: %v", term, err)
+			}
+		}
+		if err := dbinterface.Edit(ctx, dbc, "我", "me"); err != nil {
+			t.Fatalf("Error when restoring definition: %v", err)
+		}
+	})
+	if len(results[0].Ids) != 2 || results[1].Status != dbinterface.BatchApplied {
+		t.Errorf("Got results %+v, wanted 们 and 我们 added and 我 edited", results)
+	}
+	got, err := dbinterface.Find(ctx, dbc, "我")
+	if err != nil {
+		t.Fatalf("Error when finding: %v", err)
+	}
+	if got[1].Definition != "I; me" {
+		t.Errorf("Got definition %q, wanted the edit applied", got[1].Definition)
+	}
+}
+
+func TestUndoRedo(t *testing.T) {
+	ctx := context.Background()
+	dictMap := dict.DictMap{"们": {Simplified: "们", Pinyin: "men5", English: "plural marker"}}
+
+	ids, err := dbinterface.Add(ctx, dbc, "我们", dictMap)
+	if err != nil {
+		t.Fatalf("Error when adding: %v", err)
+	}
+	t.Cleanup(func() {
+		for _, term := range []string{"我们", "们"} {
+			dbinterface.Delete(ctx, dbc, term)
+			if err := dbinterface.Purge(ctx, dbc, term); err != nil {
+				t.Fatalf("Error when purging %s: %v", term, err)
+			}
+		}
+	})
+	if len(ids) != 2 {
+		t.Fatalf("Got ids %v, wanted 们 and 我们 added", ids)
+	}
+
+	// Undoing the add trashes both cards it made, but not 我 which was
+	// already there.
+	entries, err := dbinterface.Undo(ctx, dbc, 1)
+	if err != nil {
+		t.Fatalf("Error when undoing: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Op != dbinterface.JournalAdd || !reflect.DeepEqual(entries[0].Ids, ids) {
+		t.Errorf("Got entries %+v, wanted the add of %v", entries, ids)
+	}
+	for _, term := range []string{"我们", "们"} {
+		if _, err := dbinterface.Find(ctx, dbc, term); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+			t.Errorf("Got error %v, wanted %s undone", err, term)
+		}
+	}
+	if _, err := dbinterface.Find(ctx, dbc, "我"); err != nil {
+		t.Errorf("Error when finding 我 after undo: %v", err)
+	}
+
+	if _, err := dbinterface.Redo(ctx, dbc, 1); err != nil {
+		t.Fatalf("Error when redoing: %v", err)
+	}
+	got, err := dbinterface.Find(ctx, dbc, "我们")
+	if err != nil || len(got) != 1 {
+		t.Errorf("Got %v, %v, wanted 我们 back after redo", got, err)
+	}
+
+	if err := dbinterface.Edit(ctx, dbc, "我们", "we"); err != nil {
+		t.Fatalf("Error when editing: %v", err)
+	}
+	if err := dbinterface.Delete(ctx, dbc, "们"); err != nil {
+		t.Fatalf("Error when deleting: %v", err)
+	}
+	entries, err = dbinterface.Undo(ctx, dbc, 2)
+	if err != nil {
+		t.Fatalf("Error when undoing: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Op != dbinterface.JournalDelete || entries[1].Op != dbinterface.JournalEdit {
+		t.Errorf("Got entries %+v, wanted the delete then the edit", entries)
+	}
+	if _, err := dbinterface.Find(ctx, dbc, "们"); err != nil {
+		t.Errorf("Error when finding 们 after undoing its delete: %v", err)
+	}
+	got, err = dbinterface.Find(ctx, dbc, "我们")
+	if err != nil {
+		t.Fatalf("Error when finding: %v", err)
+	}
+	for _, termDef := range got {
+		if termDef.Definition == "we" {
+			t.Errorf("Got definition %q, wanted the edit undone", termDef.Definition)
+		}
+	}
+
+	// A new operation clears what could be redone.
+	if err := dbinterface.Edit(ctx, dbc, "我们", "us"); err != nil {
+		t.Fatalf("Error when editing: %v", err)
+	}
+	entries, err = dbinterface.Redo(ctx, dbc, 2)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Got %+v, %v, wanted nothing to redo", entries, err)
+	}
+	if _, err := dbinterface.Undo(ctx, dbc, 1); err != nil {
+		t.Fatalf("Error when undoing: %v", err)
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	dictMap := dict.DictMap{"们": {Simplified: "们", Pinyin: "men5", English: "plural marker"}}
+
+	ids, err := dbinterface.Add(ctx, dbc, "我们", dictMap)
+	if err != nil {
+		t.Fatalf("Error when adding: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("Got ids %v, wanted 们 and 我们 added", ids)
+	}
+	if err := dbinterface.Edit(ctx, dbc, "们", "plural suffix"); err != nil {
+		t.Fatalf("Error when editing: %v", err)
+	}
+	if err := dbinterface.Tag(ctx, dbc, "们", "grammar"); err != nil {
+		t.Fatalf("Error when tagging: %v", err)
+	}
+	card := dbinterface.Card{TermId: ids[0], Term: "们", Direction: dbinterface.TermToDefinition}
+	if err := dbinterface.RecordGrade(ctx, dbc, card, dbinterface.Unsure); err != nil {
+		t.Fatalf("Error when grading: %v", err)
+	}
+	for _, term := range []string{"们", "我们"} {
+		if err := dbinterface.Delete(ctx, dbc, term); err != nil {
+			t.Fatalf("Error when deleting %s: %v", term, err)
+		}
+		if err := dbinterface.Purge(ctx, dbc, term); err != nil {
+			t.Fatalf("Error when purging %s: %v", term, err)
+		}
+	}
+
+	// The history outlives the purged card. Earlier tests added 们 too, so
+	// only this card's events are checked.
+	history, err := dbinterface.History(ctx, dbc, "们")
+	if err != nil {
+		t.Fatalf("Error when getting history: %v", err)
+	}
+	var events []dbinterface.HistoryEvent
+	var kinds []dbinterface.HistoryKind
+	for _, event := range history {
+		if event.TermId == ids[0] {
+			events = append(events, event)
+			kinds = append(kinds, event.Kind)
+		}
+	}
+	wantKinds := []dbinterface.HistoryKind{
+		dbinterface.HistoryAdded,
+		dbinterface.HistoryEdited,
+		dbinterface.HistoryTagged,
+		dbinterface.HistoryReviewed,
+		dbinterface.HistoryDeleted,
+		dbinterface.HistoryPurged,
+	}
+	if !reflect.DeepEqual(kinds, wantKinds) {
+		t.Fatalf("Got history %v, wanted %v", kinds, wantKinds)
+	}
+	if want := `from input "我们", definition "plural marker"`; events[0].Detail != want {
+		t.Errorf("Got added detail %q, wanted %q", events[0].Detail, want)
+	}
+	if want := `"plural marker" to "plural suffix"`; events[1].Detail != want {
+		t.Errorf("Got edited detail %q, wanted %q", events[1].Detail, want)
+	}
+	if want := "term-definition graded unsure"; events[3].Detail != want {
+		t.Errorf("Got reviewed detail %q, wanted %q", events[3].Detail, want)
+	}
+
+	// An edit between two definitions of the longest length still fits in
+	// the history.
+	for _, definition := range []string{strings.Repeat("x", 255), strings.Repeat("y", 255), "me"} {
+		if err := dbinterface.Edit(ctx, dbc, "我", definition); err != nil {
+			t.Fatalf("Error when editing with a long definition: %v", err)
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+	ctx := context.Background()
+	var archive bytes.Buffer
+	if err := dbinterface.Backup(ctx, dbc, &archive); err != nil {
+		t.Fatalf("Error when backing up: %v", err)
+	}
+
+	restored, err := dbinterface.Connect(ctx, cfg, "restored")
+	if err != nil {
+		t.Fatalf("Error when connecting: %v", err)
+	}
+	if _, err := dbinterface.Add(ctx, restored, "他", dict.DictMap{}); err != nil {
+		t.Fatalf("Error when adding: %v", err)
+	}
+
+	// A damaged archive is rejected before anything is replaced.
+	damaged := bytes.Clone(archive.Bytes())
+	damaged[len(damaged)/2] ^= 0xff
+	if err := dbinterface.RestoreBackup(ctx, restored, bytes.NewReader(damaged)); err == nil {
+		t.Errorf("Restored a damaged backup")
+	}
+	if _, err := dbinterface.Find(ctx, restored, "他"); err != nil {
+		t.Errorf("Error when finding 他 after a failed restore: %v", err)
+	}
+
+	// So is an archive with a row the database refuses, here a term
+	// without its text, even though its checksums are right.
+	corrupt, err := backup.Read(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		t.Fatalf("Error when reading the backup: %v", err)
+	}
+	for _, table := range corrupt.Tables {
+		if table.Name == "" {
+			table.Rows[len(table.Rows)-1][slices.Index(table.Columns, "term")] = nil
+		}
+	}
+	var corrupted bytes.Buffer
+	if err := backup.Write(&corrupted, corrupt); err != nil {
+		t.Fatalf("Error when writing the corrupt backup: %v", err)
+	}
+	if err := dbinterface.RestoreBackup(ctx, restored, &corrupted); err == nil {
+		t.Errorf("Restored a backup with a corrupt row")
+	}
+	if got, err := dbinterface.List(ctx, restored); err != nil || len(got) != 1 {
+		t.Errorf("Got %v, %v after a failed restore, wanted only 他", got, err)
+	}
+
+	if err := dbinterface.RestoreBackup(ctx, restored, bytes.NewReader(archive.Bytes())); err != nil {
+		t.Fatalf("Error when restoring: %v", err)
+	}
+	want, err := dbinterface.List(ctx, dbc)
+	if err != nil {
+		t.Fatalf("Error when listing: %v", err)
+	}
+	got, err := dbinterface.List(ctx, restored)
+	if err != nil {
+		t.Fatalf("Error when listing: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Got %v after restoring, wanted %v", got, want)
+	}
+	history, err := dbinterface.History(ctx, restored, "们")
+	if err != nil || len(history) == 0 {
+		t.Errorf("Got history %v, %v, wanted it restored", history, err)
+	}
+
+	// The restored store carries on with the ids after the restored ones.
+	ids, err := dbinterface.Add(ctx, restored, "他", dict.DictMap{})
+	if err != nil {
+		t.Fatalf("Error when adding: %v", err)
+	}
+	for id := range want {
+		if len(ids) != 1 || ids[0] <= id {
+			t.Fatalf("Got ids %v, wanted a new id after %d", ids, id)
+		}
+	}
+
+	// A backup from before there were users or leech flags is brought up to
+	// date by the migrations.
+	var old bytes.Buffer
+	err = backup.Write(&old, &backup.Archive{
+		TableName:     "term",
+		SchemaVersion: 4,
+		Created:       time.Now(),
+		Tables: []backup.Table{
+			{Columns: []string{"id", "term", "definition", "deleted_at"}, Rows: [][]any{{1, "书", "book", nil}}},
+			{Name: "tags", Columns: []string{"id", "name"}, Rows: [][]any{{1, "leech"}}},
+			{Name: "term_tags", Columns: []string{"term_id", "tag_id"}, Rows: [][]any{{1, 1}}},
+			{Name: "lapses", Columns: []string{"term_id", "lapses"}, Rows: [][]any{{1, 9}}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error when writing an old backup: %v", err)
+	}
+	if err := dbinterface.RestoreBackup(ctx, restored, &old); err != nil {
+		t.Fatalf("Error when restoring an old backup: %v", err)
+	}
+	if got, err := dbinterface.Find(ctx, restored, "书"); err != nil || got[1].Definition != "book" {
+		t.Errorf("Got %v, %v, wanted 书 owned by the default user", got, err)
+	}
+	if leeches, err := dbinterface.Leeches(ctx, restored); err != nil || leeches[1].Lapses != 9 {
+		t.Errorf("Got leeches %v, %v, wanted 书 flagged", leeches, err)
+	}
+	if tags, err := dbinterface.Tags(ctx, restored, "书"); err != nil || len(tags) != 0 {
+		t.Errorf("Got tags %v, %v, wanted the leech tag gone", tags, err)
+	}
+}
+
+func TestUsers(t *testing.T) {
+	ctx := context.Background()
+	conns := make(map[string]*dbinterface.DatabaseConn)
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := dbinterface.CreateUser(ctx, dbc, name); err != nil {
+			t.Fatalf("Error when creating %s: %v", name, err)
+		}
+		conn, err := dbinterface.ForUser(ctx, dbc, name)
+		if err != nil {
+			t.Fatalf("Error when connecting as %s: %v", name, err)
+		}
+		conns[name] = conn
+	}
+	alice, bob := conns["alice"], conns["bob"]
+	if _, err := dbinterface.CreateUser(ctx, dbc, "alice"); !errors.As(err, new(*dbinterface.ErrUserExists)) {
+		t.Errorf("Got error %v, wanted %v", err, dbinterface.ErrUserExists{})
+	}
+
+	// Cards of the default user are not anyone else's.
+	if terms, err := dbinterface.List(ctx, alice); err != nil || len(terms) != 0 {
+		t.Errorf("Got %v, %v, wanted alice to start with no cards", terms, err)
+	}
+
+	// Both add the same word, each getting cards of their own.
+	dictMap := dict.DictMap{"好": {Simplified: "好", Pinyin: "hao3", English: "good"}}
+	aliceIds, err := dbinterface.Add(ctx, alice, "好", dictMap)
+	if err != nil {
+		t.Fatalf("Error when adding for alice: %v", err)
+	}
+	bobIds, err := dbinterface.Add(ctx, bob, "好", dictMap)
+	if err != nil {
+		t.Fatalf("Error when adding for bob: %v", err)
+	}
+	if len(aliceIds) != 1 || len(bobIds) != 1 || aliceIds[0] == bobIds[0] {
+		t.Fatalf("Got ids %v and %v, wanted a card each", aliceIds, bobIds)
+	}
+	if err := dbinterface.Edit(ctx, alice, "好", "fine"); err != nil {
+		t.Fatalf("Error when editing: %v", err)
+	}
+	got, err := dbinterface.Find(ctx, bob, "好")
+	if err != nil || got[bobIds[0]].Definition != "good" {
+		t.Errorf("Got %v, %v, wanted bob's definition untouched", got, err)
+	}
+
+	// Decks and tags with the same name do not collide.
+	for name, conn := range conns {
+		if _, err := dbinterface.CreateDeck(ctx, conn, "daily"); err != nil {
+			t.Fatalf("Error when creating a deck for %s: %v", name, err)
+		}
+	}
+	if err := dbinterface.AddToDeck(ctx, alice, "daily", "好"); err != nil {
+		t.Fatalf("Error when adding to deck: %v", err)
+	}
+	if err := dbinterface.Tag(ctx, alice, "好", "adjective"); err != nil {
+		t.Fatalf("Error when tagging: %v", err)
+	}
+	for _, filter := range []dbinterface.Filter{{Deck: "daily"}, {Tags: "adjective"}} {
+		if got, err := dbinterface.ListFiltered(ctx, bob, filter); err != nil || len(got) != 0 {
+			t.Errorf("Got %v, %v for bob with filter %+v, wanted none of alice's cards", got, err, filter)
+		}
+	}
+
+	// Reviews and stats are per user, and neither can review the other's
+	// cards.
+	card := dbinterface.Card{TermId: aliceIds[0], Term: "好", Direction: dbinterface.TermToDefinition}
+	if err := dbinterface.RecordGrade(ctx, alice, card, dbinterface.Right); err != nil {
+		t.Fatalf("Error when grading: %v", err)
+	}
+	if err := dbinterface.RecordGrade(ctx, bob, card, dbinterface.Wrong); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got error %v, wanted bob unable to grade alice's card", err)
+	}
+	if err := dbinterface.RecordAnswer(ctx, bob, aliceIds[0], "hao", false); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got error %v, wanted bob unable to answer alice's card", err)
+	}
+	if grades, err := dbinterface.LastGrades(ctx, bob, dbinterface.TermToDefinition); err != nil || len(grades) != 0 {
+		t.Errorf("Got grades %v, %v, wanted none for bob", grades, err)
+	}
+	report, err := dbinterface.Stats(ctx, bob)
+	if err != nil {
+		t.Fatalf("Error when getting stats: %v", err)
+	}
+	for _, day := range report.ReviewsPerDay {
+		t.Errorf("Got reviews %v for bob, wanted none", day)
+	}
+
+	// Deleting, undoing and the history only touch the user's own cards.
+	if err := dbinterface.Delete(ctx, bob, "好"); err != nil {
+		t.Fatalf("Error when deleting: %v", err)
+	}
+	if _, err := dbinterface.Find(ctx, alice, "好"); err != nil {
+		t.Errorf("Error when finding alice's card after bob deleted his: %v", err)
+	}
+	entries, err := dbinterface.Undo(ctx, alice, 10)
+	if err != nil {
+		t.Fatalf("Error when undoing: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Op == dbinterface.JournalDelete {
+			t.Errorf("Alice undid bob's delete")
+		}
+	}
+	if trash, err := dbinterface.Trash(ctx, bob); err != nil || len(trash) != 1 {
+		t.Errorf("Got trash %v, %v, wanted bob's card still in it", trash, err)
+	}
+	history, err := dbinterface.History(ctx, bob, "好")
+	if err != nil {
+		t.Fatalf("Error when getting history: %v", err)
+	}
+	for _, event := range history {
+		if event.TermId != bobIds[0] {
+			t.Errorf("Got event %+v in bob's history, wanted only his card", event)
+		}
+	}
+
+	// A shared card can be copied, with its definition, but not changed.
+	if _, err := dbinterface.Add(ctx, alice, "好", dictMap); err != nil {
+		t.Fatalf("Error when adding again: %v", err)
+	}
+	if err := dbinterface.Edit(ctx, alice, "好", "fine"); err != nil {
+		t.Fatalf("Error when editing: %v", err)
+	}
+	if err := dbinterface.Share(ctx, alice, "好", true); err != nil {
+		t.Fatalf("Error when sharing: %v", err)
+	}
+	library, err := dbinterface.Library(ctx, bob)
+	if err != nil {
+		t.Fatalf("Error when listing the library: %v", err)
+	}
+	want := map[int64]dbinterface.LibraryCard{aliceIds[0]: {Term: "好", Definition: "fine", Owner: "alice"}}
+	if !reflect.DeepEqual(library, want) {
+		t.Errorf("Got library %v, wanted %v", library, want)
+	}
+	if err := dbinterface.Purge(ctx, bob, "好"); err != nil {
+		t.Fatalf("Error when purging: %v", err)
+	}
+	ids, err := dbinterface.AddFromLibrary(ctx, bob, aliceIds[0], dictMap)
+	if err != nil {
+		t.Fatalf("Error when adding from the library: %v", err)
+	}
+	got, err = dbinterface.Find(ctx, bob, "好")
+	if err != nil || len(ids) != 1 || got[ids[0]].Definition != "fine" {
+		t.Errorf("Got %v, %v, wanted bob's own copy of the shared card", got, err)
+	}
+	// The import is one operation to undo and redo.
+	if entries, err := dbinterface.Undo(ctx, bob, 1); err != nil || len(entries) != 1 || entries[0].Op != dbinterface.JournalImport {
+		t.Fatalf("Got %v, %v, wanted the import undone", entries, err)
+	}
+	if got, err := dbinterface.Find(ctx, bob, "好"); !errors.As(err, new(*dbinterface.ErrNotFound)) {
+		t.Errorf("Got %v, %v, wanted bob's copy gone after undo", got, err)
+	}
+	if _, err := dbinterface.Redo(ctx, bob, 1); err != nil {
+		t.Fatalf("Error when redoing: %v", err)
+	}
+	got, err = dbinterface.Find(ctx, bob, "好")
+	if err != nil || got[ids[0]].Definition != "fine" {
+		t.Errorf("Got %v, %v, wanted bob's copy back after redo", got, err)
+	}
+	if err := dbinterface.Edit(ctx, bob, "好", "nice"); err != nil {
+		t.Fatalf("Error when editing: %v", err)
+	}
+	got, err = dbinterface.Find(ctx, alice, "好")
+	if err != nil || got[aliceIds[0]].Definition != "fine" {
+		t.Errorf("Got %v, %v, wanted alice's card untouched", got, err)
+	}
+
+	// Importing a word bob already has only adds the characters he lacks
+	// and keeps his definition.
+	if _, err := dbinterface.Add(ctx, bob, "你好", dictMap); err != nil {
+		t.Fatalf("Error when adding: %v", err)
+	}
+	if err := dbinterface.Edit(ctx, bob, "你好", "hi"); err != nil {
+		t.Fatalf("Error when editing: %v", err)
+	}
+	if err := dbinterface.Delete(ctx, bob, "你"); err != nil {
+		t.Fatalf("Error when deleting: %v", err)
+	}
+	if err := dbinterface.Purge(ctx, bob, "你"); err != nil {
+		t.Fatalf("Error when purging: %v", err)
+	}
+	sharedIds, err := dbinterface.Add(ctx, alice, "你好", dictMap)
+	if err != nil {
+		t.Fatalf("Error when adding: %v", err)
+	}
+	if err := dbinterface.Share(ctx, alice, "你好", true); err != nil {
+		t.Fatalf("Error when sharing: %v", err)
+	}
+	if ids, err := dbinterface.AddFromLibrary(ctx, bob, sharedIds[len(sharedIds)-1], dictMap); err != nil || len(ids) != 1 {
+		t.Fatalf("Got %v, %v, wanted only 你 added", ids, err)
+	}
+	got, err = dbinterface.Find(ctx, bob, "你好")
+	if err != nil {
+		t.Fatalf("Error when finding: %v", err)
+	}
+	for _, termDef := range got {
+		if termDef.Term == "你好" && termDef.Definition != "hi" {
+			t.Errorf("Got definition %q, wanted bob's own kept", termDef.Definition)
+		}
+	}
+}
+
+func TestHTTPAPI(t *testing.T) {
+	dictMap := dict.DictMap{
+		"书": {Simplified: "书", Pinyin: "shu1", English: "book"},
+		"笔": {Simplified: "笔", Pinyin: "bi3", English: "pen"},
+	}
+	server := httptest.NewServer(api.New(dbc, dictMap, api.Options{Signup: true}))
+	defer server.Close()
+
+	// do sends a request with a bearer token, or with the client's session
+	// cookie if token is empty, and decodes the response into out.
+	do := func(client *http.Client, method, path, token, body string, out any) int {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		if out != nil && resp.StatusCode < 300 {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatalf("%s %s: %v", method, path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+	newClient := func() *http.Client {
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &http.Client{Jar: jar}
+	}
+
+	carol, dave := newClient(), newClient()
+	for name, client := range map[string]*http.Client{"carol": carol, "dave": dave} {
+		body := fmt.Sprintf(`{"name": %q, "password": "correct horse"}`, name)
+		if status := do(client, "POST", "/accounts", "", body, nil); status != http.StatusCreated {
+			t.Fatalf("Got status %d when signing up %s", status, name)
+		}
+		if status := do(client, "POST", "/login", "", fmt.Sprintf(`{"name": %q, "password": "wrong horse"}`, name), nil); status != http.StatusUnauthorized {
+			t.Errorf("Got status %d when logging in with a wrong password", status)
+		}
+		if status := do(client, "POST", "/login", "", body, nil); status != http.StatusNoContent {
+			t.Fatalf("Got status %d when logging in %s", status, name)
+		}
+	}
+	if status := do(newClient(), "POST", "/accounts", "", `{"name": "carol", "password": "another one"}`, nil); status != http.StatusConflict {
+		t.Errorf("Got status %d when signing up carol twice", status)
+	}
+	if status := do(newClient(), "GET", "/terms", "", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("Got status %d without credentials", status)
+	}
+
+	// A session can add, edit and undo.
+	var added map[string][]int64
+	if status := do(carol, "POST", "/terms", "", `{"term": "书"}`, &added); status != http.StatusCreated || len(added["ids"]) != 1 {
+		t.Fatalf("Got status %d and %v when adding", status, added)
+	}
+	if status := do(carol, "PUT", "/terms/书", "", `{"definition": "a book"}`, nil); status != http.StatusNoContent {
+		t.Errorf("Got status %d when editing", status)
+	}
+	var undone []api.Operation
+	if status := do(carol, "POST", "/undo", "", "", &undone); status != http.StatusOK || len(undone) != 1 || undone[0].Op != "edit" {
+		t.Errorf("Got status %d and %+v when undoing", status, undone)
+	}
+	var terms []api.Term
+	do(carol, "GET", "/terms", "", "", &terms)
+	want := []api.Term{{Id: added["ids"][0], Term: "书", Definition: "book"}}
+	if !reflect.DeepEqual(terms, want) {
+		t.Errorf("Got %+v; wanted %+v", terms, want)
+	}
+	var events []api.Event
+	if status := do(carol, "GET", "/terms/书/history", "", "", &events); status != http.StatusOK || len(events) == 0 {
+		t.Errorf("Got status %d and %v for the history", status, events)
+	}
+
+	// A read token can read but not write, nor make a write token.
+	var created struct{ Token string }
+	if status := do(carol, "POST", "/tokens", "", `{"permission": "read"}`, &created); status != http.StatusCreated {
+		t.Fatalf("Got status %d when creating a token", status)
+	}
+	token := created.Token
+	client := http.DefaultClient
+	if status := do(client, "GET", "/terms", token, "", &terms); status != http.StatusOK || len(terms) != 1 {
+		t.Errorf("Got status %d and %v with a read token", status, terms)
+	}
+	if status := do(client, "POST", "/terms", token, `{"term": "笔"}`, nil); status != http.StatusForbidden {
+		t.Errorf("Got status %d when adding with a read token", status)
+	}
+	if status := do(client, "POST", "/tokens", token, `{"permission": "write"}`, nil); status != http.StatusForbidden {
+		t.Errorf("Got status %d when making a write token with a read token", status)
+	}
+	if status := do(client, "DELETE", "/tokens", token, fmt.Sprintf(`{"token": %q}`, token), nil); status != http.StatusNoContent {
+		t.Errorf("Got status %d when revoking the token", status)
+	}
+	if status := do(client, "GET", "/terms", token, "", nil); status != http.StatusUnauthorized {
+		t.Errorf("Got status %d with a revoked token", status)
+	}
+
+	// Dave needs a grant to see carol's cards, and a write grant to
+	// change them.
+	if status := do(dave, "GET", "/terms?owner=carol", "", "", nil); status != http.StatusForbidden {
+		t.Errorf("Got status %d for dave without a grant", status)
+	}
+	if status := do(carol, "PUT", "/grants/dave", "", `{"permission": "read"}`, nil); status != http.StatusNoContent {
+		t.Fatalf("Got status %d when granting", status)
+	}
+	if status := do(dave, "GET", "/terms?owner=carol", "", "", &terms); status != http.StatusOK || len(terms) != 1 {
+		t.Errorf("Got status %d and %v for dave with a read grant", status, terms)
+	}
+	if status := do(dave, "DELETE", "/terms/书?owner=carol", "", "", nil); status != http.StatusForbidden {
+		t.Errorf("Got status %d when dave deletes with a read grant", status)
+	}
+	do(carol, "PUT", "/grants/dave", "", `{"permission": "write"}`, nil)
+	if status := do(dave, "DELETE", "/terms/书?owner=carol", "", "", nil); status != http.StatusNoContent {
+		t.Errorf("Got status %d when dave deletes with a write grant", status)
+	}
+	do(carol, "DELETE", "/grants/dave", "", "", nil)
+	if status := do(dave, "POST", "/undo?owner=carol", "", "", nil); status != http.StatusForbidden {
+		t.Errorf("Got status %d for dave after the grant was revoked", status)
+	}
+	if status := do(carol, "POST", "/undo", "", "", &undone); status != http.StatusOK || len(undone) != 1 || undone[0].Op != "delete" {
+		t.Errorf("Got status %d and %+v when carol undoes dave's delete", status, undone)
+	}
+
+	// Logging out ends the session.
+	if status := do(carol, "POST", "/logout", "", "", nil); status != http.StatusNoContent {
+		t.Errorf("Got status %d when logging out", status)
+	}
+	if status := do(carol, "GET", "/terms", "", "", nil); status != http.StatusUnauthorized {
+		t.Errorf("Got status %d after logging out", status)
+	}
+
+	// Logging in clears out expired sessions.
+	ctx := context.Background()
+	carolConn, err := dbinterface.ForUser(ctx, dbc, "carol")
+	if err != nil {
+		t.Fatalf("Error when connecting as carol: %v", err)
+	}
+	expired, err := dbinterface.CreateToken(ctx, carolConn, dbinterface.SessionToken, dbinterface.Write, time.Millisecond)
+	if err != nil {
+		t.Fatalf("Error when creating a session: %v", err)
+	}
+	time.Sleep(2 * time.Second)
+	if _, err := dbinterface.Login(ctx, dbc, "dave", "correct horse"); err != nil {
+		t.Fatalf("Error when logging in: %v", err)
+	}
+	if err := dbinterface.RevokeToken(ctx, carolConn, expired); !errors.As(err, new(*dbinterface.ErrInvalidCredentials)) {
+		t.Errorf("Got error %v, wanted the expired session deleted", err)
+	}
+}